@@ -0,0 +1,105 @@
+package vector
+
+// builder is a transient vector. Elements appended to a builder are written in
+// place, without copying any path of the trie, and the resulting persistent
+// vector is assembled only once, when vector is called. A builder must not be
+// used after vector has been called.
+type builder struct {
+	count  uint64
+	leaves []*node
+	tail   []interface{}
+}
+
+// newBuilder returns a new builder with room for the given number of elements.
+func newBuilder(size int) *builder {
+	if size < 0 {
+		size = 0
+	}
+	return &builder{
+		leaves: make([]*node, 0, size>>vectorBits),
+		tail:   make([]interface{}, 0, vectorWidth),
+	}
+}
+
+// append adds an element at the end of the builder.
+func (b *builder) append(elem interface{}) {
+	if len(b.tail) == int(vectorWidth) {
+		b.leaves = append(b.leaves, &node{b.tail})
+		b.tail = make([]interface{}, 0, vectorWidth)
+	}
+	b.tail = append(b.tail, elem)
+	b.count++
+}
+
+// appendLeaf adds the elements of a full leaf at the end of the builder,
+// reusing the leaf instead of copying them. The number of elements in the
+// builder must be a multiple of 32.
+func (b *builder) appendLeaf(leaf *node) {
+	if len(b.tail) > 0 {
+		b.leaves = append(b.leaves, &node{b.tail})
+		b.tail = make([]interface{}, 0, vectorWidth)
+	}
+	b.leaves = append(b.leaves, leaf)
+	b.count += uint64(vectorWidth)
+}
+
+// vector returns the persistent vector with all the elements in the builder.
+func (b *builder) vector() *Vector {
+	if b.count == 0 {
+		return &Vector{0, uint(vectorBits), emptyNode, &node{nil}, 0}
+	}
+
+	if len(b.tail) == 0 {
+		last := b.leaves[len(b.leaves)-1]
+		b.leaves = b.leaves[:len(b.leaves)-1]
+		return b.vectorWithTail(last)
+	}
+	return b.vectorWithTail(&node{b.tail})
+}
+
+// vectorWithTail returns the persistent vector with the leaves in the builder
+// and the given tail.
+func (b *builder) vectorWithTail(tail *node) *Vector {
+
+	shift := uint(vectorBits)
+	for uint64(len(b.leaves)) > 1<<shift {
+		shift += uint(vectorBits)
+	}
+
+	root := emptyNode
+	if len(b.leaves) > 0 {
+		root = buildTree(b.leaves, shift)
+	}
+
+	return &Vector{b.count, shift, root, tail, 0}
+}
+
+// buildTree returns a node at the given level containing all the given leaves.
+func buildTree(leaves []*node, shift uint) *node {
+	n := &node{make([]interface{}, vectorWidth)}
+	if shift == uint(vectorBits) {
+		for i, l := range leaves {
+			n.values[i] = l
+		}
+		return n
+	}
+
+	size := 1 << (shift - uint(vectorBits))
+	for i := 0; i*size < len(leaves); i++ {
+		end := (i + 1) * size
+		if end > len(leaves) {
+			end = len(leaves)
+		}
+		n.values[i] = buildTree(leaves[i*size:end], shift-uint(vectorBits))
+	}
+	return n
+}
+
+// fromSlice returns a new vector with the given elements.
+func fromSlice(elems []interface{}) *Vector {
+	b := newBuilder(len(elems))
+	for _, e := range elems {
+		b.append(e)
+	}
+	return b.vector()
+}
@@ -0,0 +1,41 @@
+package vector
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuilder(t *testing.T) {
+	for _, n := range []int{0, 1, 31, 32, 33, 64, 1024, 1056, 1057, 5000} {
+		b := newBuilder(n)
+		for i := 0; i < n; i++ {
+			b.append(i)
+		}
+		v := b.vector()
+
+		expected := makeVector(n)
+		require.Equal(t, expected.shift, v.shift, "size %d", n)
+		require.True(t, Equal(expected, v), "size %d", n)
+		require.True(t, Equal(expected.Append(-1), v.Append(-1)), "size %d", n)
+	}
+}
+
+func TestBuilderAppendLeaf(t *testing.T) {
+	require := require.New(t)
+
+	v := makeVector(100)
+	for _, n := range []int{0, 1, 32} {
+		b := newBuilder(64 + n)
+		b.appendLeaf(v.leafFor(0))
+		b.appendLeaf(v.leafFor(32))
+		for i := 0; i < n; i++ {
+			b.append(64 + i)
+		}
+		result := b.vector()
+
+		require.True(Equal(v.sub(0, 64+n), result), "size %d", 64+n)
+		require.True(SharesStructure(v, result), "size %d", 64+n)
+		require.True(Equal(v.sub(0, 64+n).Append(-1), result.Append(-1)), "size %d", 64+n)
+	}
+}
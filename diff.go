@@ -0,0 +1,408 @@
+package vector
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// EditOp is the kind of operation performed by an Edit.
+type EditOp int
+
+const (
+	// EditInsert inserts an element.
+	EditInsert EditOp = iota
+	// EditDelete deletes an element.
+	EditDelete
+	// EditReplace replaces an element with another.
+	EditReplace
+)
+
+func (op EditOp) String() string {
+	switch op {
+	case EditInsert:
+		return "insert"
+	case EditDelete:
+		return "delete"
+	case EditReplace:
+		return "replace"
+	default:
+		return "unknown"
+	}
+}
+
+// Edit is a single operation of an edit script that turns a vector into
+// another.
+type Edit struct {
+	// Op is the kind of edit.
+	Op EditOp
+	// Index is the position in the source vector the edit applies to.
+	// Insertions happen right before the element at that position.
+	Index int
+	// NewIndex is the position in the target vector of the inserted or
+	// replacing element. For deletions, it's the position in the target vector
+	// where the deleted element would be.
+	NewIndex int
+	// Value is the inserted or replacing element. It's nil for deletions.
+	Value interface{}
+}
+
+// Diff returns a minimal edit script that turns v1 into v2, sorted by index.
+// Elements are compared using the given function or reflect.DeepEqual if the
+// function is nil. The script has the fewest possible insertions and
+// deletions, and every deletion followed by an insertion at the same position
+// is turned into a replacement.
+//
+// The elements both vectors start and end with are skipped first, without
+// comparing the leaves shared by both, so diffing two versions of a vector
+// only costs as much as the part that changed. The rest is compared using
+// the linear space variant of the Myers diff algorithm, which takes
+// O((n+m)*d) time, where d is the number of edits, and O(n+m) memory.
+func Diff(v1, v2 *Vector, eq EqualFn) []Edit {
+	if eq == nil {
+		eq = reflect.DeepEqual
+	}
+
+	if v1 == nil {
+		v1 = emptyVector
+	}
+	if v2 == nil {
+		v2 = emptyVector
+	}
+
+	n, m := v1.Count(), v2.Count()
+	common := n
+	if m < common {
+		common = m
+	}
+
+	prefix := commonPrefix(v1, v2, common, eq)
+	var suffix int
+	if v1.count == v2.count {
+		suffix = commonSuffix(v1, v2, common-prefix, eq)
+	}
+
+	d := &differ{
+		a:      v1.elements(prefix, n-suffix),
+		b:      v2.elements(prefix, m-suffix),
+		eq:     eq,
+		offset: prefix,
+	}
+	d.compare(0, len(d.a), 0, len(d.b))
+	d.flush()
+	return d.edits
+}
+
+// commonPrefix returns how many of the first n elements of v1 and v2 are
+// equal before the first one that is not. When both vectors have the same
+// offset, leaves shared by both are skipped without comparing their elements.
+func commonPrefix(v1, v2 *Vector, n int, eq EqualFn) int {
+	if v1.start != v2.start {
+		c1, c2 := &cursor{v: v1}, &cursor{v: v2}
+		for i := 0; i < n; i++ {
+			a, _ := c1.next()
+			b, _ := c2.next()
+			if !eq(a, b) {
+				return i
+			}
+		}
+		return n
+	}
+
+	start := uint64(v1.start)
+	for key, end := start, start+uint64(n); key < end; {
+		next := (key | uint64(vectorMask)) + 1
+		if next > end {
+			next = end
+		}
+
+		a, b := v1.leafFor(key), v2.leafFor(key)
+		if a == b {
+			key = next
+			continue
+		}
+
+		for ; key < next; key++ {
+			i := key & uint64(vectorMask)
+			if !eq(a.values[i], b.values[i]) {
+				return int(key - start)
+			}
+		}
+	}
+	return n
+}
+
+// commonSuffix returns how many of the last n elements of v1 and v2 are equal
+// after the last one that is not. Both vectors must have the same layout at
+// the end, that is, the same count, so leaves shared by both are skipped
+// without comparing their elements.
+func commonSuffix(v1, v2 *Vector, n int, eq EqualFn) int {
+	end := v1.count
+	for key, lo := end, end-uint64(n); key > lo; {
+		prev := (key - 1) &^ uint64(vectorMask)
+		if prev < lo {
+			prev = lo
+		}
+
+		a, b := v1.leafFor(key-1), v2.leafFor(key-1)
+		if a == b {
+			key = prev
+			continue
+		}
+
+		for ; key > prev; key-- {
+			i := (key - 1) & uint64(vectorMask)
+			if !eq(a.values[i], b.values[i]) {
+				return int(end - key)
+			}
+		}
+	}
+	return n
+}
+
+// differ computes the edit script between a and b. The elements before a and
+// b in the vectors being compared are given by offset.
+type differ struct {
+	a, b   []interface{}
+	eq     EqualFn
+	offset int
+	edits  []Edit
+	// pending is the region of a and b, as [aLo, aHi, bLo, bHi], whose
+	// elements are deleted and inserted, respectively, that has not been
+	// added to edits yet, so it can grow with the following regions.
+	pending [4]int
+}
+
+// compare adds the edits that turn a[aLo:aHi] into b[bLo:bHi], in order.
+func (d *differ) compare(aLo, aHi, bLo, bHi int) {
+	for aLo < aHi && bLo < bHi && d.eq(d.a[aLo], d.b[bLo]) {
+		aLo++
+		bLo++
+	}
+	for aLo < aHi && bLo < bHi && d.eq(d.a[aHi-1], d.b[bHi-1]) {
+		aHi--
+		bHi--
+	}
+
+	if aLo == aHi || bLo == bHi {
+		d.change(aLo, aHi, bLo, bHi)
+		return
+	}
+
+	x, y := d.middleSnake(aLo, aHi, bLo, bHi)
+	d.compare(aLo, x, bLo, y)
+	d.compare(x, aHi, y, bHi)
+}
+
+// middleSnake returns a point of a shortest path to turn a[aLo:aHi] into
+// b[bLo:bHi] that splits it in two halves with the same number of edits, give
+// or take one. Both ranges must not be empty and their first and last
+// elements must differ, so the path has at least two edits.
+func (d *differ) middleSnake(aLo, aHi, bLo, bHi int) (int, int) {
+	n, m := aHi-aLo, bHi-bLo
+	delta := n - m
+	odd := delta&1 != 0
+	max := (n + m + 1) / 2
+
+	// forward[off+k] and backward[off+k] are the furthest x reached on the
+	// diagonal k, where x-y is k, from the start and from the end of the
+	// ranges, respectively.
+	off := max + 1
+	forward := make([]int, 2*max+3)
+	backward := make([]int, 2*max+3)
+	for e := 0; e <= max; e++ {
+		for k := -e; k <= e; k += 2 {
+			var x int
+			if k == -e || (k != e && forward[off+k-1] < forward[off+k+1]) {
+				x = forward[off+k+1]
+			} else {
+				x = forward[off+k-1] + 1
+			}
+
+			y := x - k
+			for x < n && y < m && d.eq(d.a[aLo+x], d.b[bLo+y]) {
+				x++
+				y++
+			}
+			forward[off+k] = x
+
+			if rk := delta - k; odd && rk >= -(e-1) && rk <= e-1 && x+backward[off+rk] >= n {
+				return aLo + x, bLo + y
+			}
+		}
+
+		for k := -e; k <= e; k += 2 {
+			var x int
+			if k == -e || (k != e && backward[off+k-1] < backward[off+k+1]) {
+				x = backward[off+k+1]
+			} else {
+				x = backward[off+k-1] + 1
+			}
+
+			y := x - k
+			for x < n && y < m && d.eq(d.a[aHi-1-x], d.b[bHi-1-y]) {
+				x++
+				y++
+			}
+			backward[off+k] = x
+
+			if fk := delta - k; !odd && fk >= -e && fk <= e && x+forward[off+fk] >= n {
+				return aHi - x, bHi - y
+			}
+		}
+	}
+
+	panic("vector: no middle snake found")
+}
+
+// change records that a[aLo:aHi] is deleted and b[bLo:bHi] inserted in its
+// place, merging the change with the pending one if they are contiguous.
+func (d *differ) change(aLo, aHi, bLo, bHi int) {
+	if aLo == aHi && bLo == bHi {
+		return
+	}
+
+	p := &d.pending
+	if p[0] != p[1] || p[2] != p[3] {
+		if p[1] == aLo && p[3] == bLo {
+			p[1], p[3] = aHi, bHi
+			return
+		}
+		d.flush()
+	}
+	*p = [4]int{aLo, aHi, bLo, bHi}
+}
+
+// flush adds the pending change to the edits, replacing as many elements as
+// possible and deleting or inserting the rest.
+func (d *differ) flush() {
+	aLo, aHi, bLo, bHi := d.pending[0], d.pending[1], d.pending[2], d.pending[3]
+	d.pending = [4]int{}
+
+	i, j := aLo, bLo
+	for ; i < aHi && j < bHi; i, j = i+1, j+1 {
+		d.edits = append(d.edits, Edit{EditReplace, d.offset + i, d.offset + j, d.b[j]})
+	}
+	for ; i < aHi; i++ {
+		d.edits = append(d.edits, Edit{EditDelete, d.offset + i, d.offset + j, nil})
+	}
+	for ; j < bHi; j++ {
+		d.edits = append(d.edits, Edit{EditInsert, d.offset + i, d.offset + j, d.b[j]})
+	}
+}
+
+// DiffIndices returns the positions at which the elements of v1 and v2 differ,
+// in ascending order. Elements are compared using the given function or
+// reflect.DeepEqual if the function is nil. If one vector is longer than the
+// other, all positions past the end of the shorter one are included as well.
+//
+// When both vectors have the same offset, as with vectors derived from one
+// another through Set or Append, leaves shared by both are skipped without
+// comparing their elements.
+func DiffIndices(v1, v2 *Vector, eq EqualFn) []int {
+	if eq == nil {
+		eq = reflect.DeepEqual
+	}
+
+	len1, len2 := v1.Count(), v2.Count()
+	n, longest := len1, len2
+	if n > longest {
+		n, longest = longest, n
+	}
+
+	var result []int
+	if n > 0 && v1.start == v2.start {
+		start := uint64(v1.start)
+		for key, end := start, start+uint64(n); key < end; {
+			next := (key | uint64(vectorMask)) + 1
+			if next > end {
+				next = end
+			}
+
+			a, b := v1.leafFor(key), v2.leafFor(key)
+			if a == b {
+				key = next
+				continue
+			}
+
+			for ; key < next; key++ {
+				i := key & uint64(vectorMask)
+				if !eq(a.values[i], b.values[i]) {
+					result = append(result, int(key-start))
+				}
+			}
+		}
+	} else if n > 0 {
+		c1, c2 := &cursor{v: v1}, &cursor{v: v2}
+		for i := 0; i < n; i++ {
+			a, _ := c1.next()
+			b, _ := c2.next()
+			if !eq(a, b) {
+				result = append(result, i)
+			}
+		}
+	}
+
+	for i := n; i < longest; i++ {
+		result = append(result, i)
+	}
+	return result
+}
+
+// EqualMask returns a new vector of booleans with as many elements as the
+// shorter of the vector and other, each one reporting whether both have the
+// same element at that position. Elements are compared using the given
+// function or reflect.DeepEqual if the function is nil.
+func (v *Vector) EqualMask(other *Vector, eq EqualFn) *Vector {
+	if eq == nil {
+		eq = reflect.DeepEqual
+	}
+
+	n := v.Count()
+	if m := other.Count(); m < n {
+		n = m
+	}
+
+	b := newBuilder(n)
+	c1, c2 := &cursor{v: v}, &cursor{v: other}
+	for i := 0; i < n; i++ {
+		x, _ := c1.next()
+		y, _ := c2.next()
+		b.append(eq(x, y))
+	}
+	return b.vector()
+}
+
+// Apply returns a new vector resulting of applying the given edit script to
+// the vector, such as the ones returned by Diff. Edits must be sorted by index
+// and refer to positions in the current vector, otherwise it will panic.
+func (v *Vector) Apply(edits []Edit) *Vector {
+	n := v.Count()
+	b := newBuilder(n)
+	var i int
+	for _, e := range edits {
+		if e.Index < i || e.Index > n || (e.Op != EditInsert && e.Index == n) {
+			panic(fmt.Errorf("vector: invalid %s edit at index %d of a vector "+
+				"with %d elements", e.Op, e.Index, n))
+		}
+
+		for ; i < e.Index; i++ {
+			b.append(v.Get(i))
+		}
+
+		switch e.Op {
+		case EditInsert:
+			b.append(e.Value)
+		case EditDelete:
+			i++
+		case EditReplace:
+			b.append(e.Value)
+			i++
+		}
+	}
+
+	for ; i < n; i++ {
+		b.append(v.Get(i))
+	}
+
+	return b.vector()
+}
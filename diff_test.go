@@ -0,0 +1,218 @@
+package vector
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	testCases := []struct {
+		name     string
+		v1, v2   *Vector
+		expected []Edit
+	}{
+		{"equal", New(1, 2, 3), New(1, 2, 3), nil},
+		{"empty", New(), New(), nil},
+		{
+			"delete",
+			New(1, 2, 3),
+			New(1, 3),
+			[]Edit{{EditDelete, 1, 1, nil}},
+		},
+		{
+			"replace",
+			New(1, 2, 3),
+			New(1, 4, 3),
+			[]Edit{{EditReplace, 1, 1, 4}},
+		},
+		{
+			"insert",
+			New(1, 2),
+			New(0, 1, 2, 3),
+			[]Edit{
+				{EditInsert, 0, 0, 0},
+				{EditInsert, 2, 3, 3},
+			},
+		},
+		{
+			"from empty",
+			New(),
+			New(1, 2),
+			[]Edit{
+				{EditInsert, 0, 0, 1},
+				{EditInsert, 0, 1, 2},
+			},
+		},
+		{
+			"mixed",
+			New(1, 2, 3, 4),
+			New(2, 3, 5, 4, 6),
+			[]Edit{
+				{EditDelete, 0, 0, nil},
+				{EditInsert, 3, 2, 5},
+				{EditInsert, 4, 4, 6},
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, Diff(tt.v1, tt.v2, nil))
+		})
+	}
+}
+
+func TestDiffMinimal(t *testing.T) {
+	require := require.New(t)
+
+	lcs := func(a, b []interface{}) int {
+		prev := make([]int, len(b)+1)
+		for i := range a {
+			cur := make([]int, len(b)+1)
+			for j := range b {
+				switch {
+				case a[i] == b[j]:
+					cur[j+1] = prev[j] + 1
+				case prev[j+1] > cur[j]:
+					cur[j+1] = prev[j+1]
+				default:
+					cur[j+1] = cur[j]
+				}
+			}
+			prev = cur
+		}
+		return prev[len(b)]
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	random := func() *Vector {
+		b := newBuilder(0)
+		for i, n := 0, rng.Intn(40); i < n; i++ {
+			b.append(rng.Intn(3))
+		}
+		return b.vector()
+	}
+
+	for i := 0; i < 500; i++ {
+		v1, v2 := random(), random()
+		edits := Diff(v1, v2, nil)
+		require.True(Equal(v2, v1.Apply(edits)), "%s -> %s", v1, v2)
+
+		var cost int
+		for _, e := range edits {
+			if e.Op == EditReplace {
+				cost += 2
+			} else {
+				cost++
+			}
+		}
+		expected := v1.Count() + v2.Count() - 2*lcs(v1.Slice(), v2.Slice())
+		require.Equal(expected, cost, "%s -> %s", v1, v2)
+	}
+}
+
+func TestDiffShared(t *testing.T) {
+	require := require.New(t)
+
+	var calls int
+	eq := func(a, b interface{}) bool {
+		calls++
+		return a == b
+	}
+
+	v := makeVector(10000)
+	edits := Diff(v, v.Set(5000, -1), eq)
+	require.Equal([]Edit{{EditReplace, 5000, 5000, -1}}, edits)
+	require.True(calls <= 2*int(vectorWidth))
+
+	edits = Diff(v.Drop(10), v.Set(20, -1).Append(-2).Drop(10), nil)
+	require.Equal([]Edit{
+		{EditReplace, 10, 10, -1},
+		{EditInsert, 9990, 9990, -2},
+	}, edits)
+
+	require.Equal([]Edit{{EditInsert, 0, 0, 1}}, Diff(nil, New(1), nil))
+}
+
+func TestDiffIndices(t *testing.T) {
+	require := require.New(t)
+
+	require.Equal([]int{1, 3}, DiffIndices(New(1, 2, 3, 4), New(1, 9, 3, 9), nil))
+	require.Equal([]int{1, 3, 4}, DiffIndices(New(1, 2, 3, 4, 5), New(1, 9, 3, 9), nil))
+	require.Equal([]int{0, 1}, DiffIndices(New(), New(1, 2), nil))
+	require.Empty(DiffIndices(New(1, 2), New(1, 2), nil))
+
+	var calls int
+	eq := func(a, b interface{}) bool {
+		calls++
+		return a == b
+	}
+
+	big := makeVector(2000)
+	changed := big.Set(1500, -1).Append(2000)
+	require.Equal([]int{1500, 2000}, DiffIndices(big, changed, eq))
+	require.True(calls <= 3*int(vectorWidth))
+
+	calls = 0
+	require.Equal([]int{1000, 1499, 1999}, DiffIndices(big.Drop(1), changed.Set(1001, 0).Drop(1), eq))
+	require.True(calls <= 3*int(vectorWidth))
+
+	require.Equal([]int{2}, DiffIndices(New(0, 1, 2, 3).Drop(1), New(1, 2, 9), nil))
+}
+
+func TestEqualMask(t *testing.T) {
+	require := require.New(t)
+
+	mask := New(1, 2, 3).EqualMask(New(1, 9, 3), nil)
+	require.True(Equal(New(true, false, true), mask))
+
+	mask = New(1, 2, 3).EqualMask(New(1, 2), nil)
+	require.True(Equal(New(true, true), mask))
+
+	mask = makeVector(100).Drop(50).EqualMask(makeVector(100).Set(60, -1).Drop(50), nil)
+	require.Equal(50, mask.Count())
+	require.Equal(false, mask.Get(10))
+	require.Len(mask.IndicesOf(func(x interface{}) bool { return x == true }), 49)
+
+	require.Equal(0, New().EqualMask(New(1), nil).Count())
+}
+
+func TestApply(t *testing.T) {
+	require := require.New(t)
+
+	v := New(1, 2, 3)
+	require.True(Equal(New(0, 1, 2, 3, 4), v.Apply([]Edit{
+		{Op: EditInsert, Index: 0, Value: 0},
+		{Op: EditInsert, Index: 3, Value: 4},
+	})))
+	require.True(Equal(New(2), v.Apply([]Edit{
+		{Op: EditDelete, Index: 0},
+		{Op: EditDelete, Index: 2},
+	})))
+	require.True(Equal(New(1, 5, 3), v.Apply([]Edit{
+		{Op: EditReplace, Index: 1, Value: 5},
+	})))
+	require.True(Equal(v, v.Apply(nil)))
+
+	require.Panics(func() {
+		v.Apply([]Edit{{Op: EditDelete, Index: 3}})
+	})
+	require.Panics(func() {
+		v.Apply([]Edit{
+			{Op: EditDelete, Index: 2},
+			{Op: EditDelete, Index: 1},
+		})
+	})
+
+	pairs := [][2]*Vector{
+		{New(1, 2, 3, 4), New(2, 3, 5, 4, 6)},
+		{New(), New(1, 2, 3)},
+		{New(1, 2, 3), New()},
+		{makeVector(100), makeVector(100).Drop(10).Set(5, -1)},
+	}
+	for _, p := range pairs {
+		require.True(Equal(p[1], p[0].Apply(Diff(p[0], p[1], nil))))
+	}
+}
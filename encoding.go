@@ -0,0 +1,41 @@
+package vector
+
+import (
+	"encoding/gob"
+	"io"
+)
+
+// EncodeElements writes all the elements of the vector in order to w using
+// encoding/gob. Each element is written in its own length-prefixed gob
+// message, so the vector can be streamed without holding the whole encoded
+// output in memory. As with any interface value encoded with gob, the concrete
+// types of the elements must be registered with gob.Register, unless they are
+// basic types.
+func (v *Vector) EncodeElements(w io.Writer) error {
+	enc := gob.NewEncoder(w)
+	for i := 0; i < v.Count(); i++ {
+		elem := v.Get(i)
+		if err := enc.Encode(&elem); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DecodeElements reads elements encoded by EncodeElements from r until EOF
+// and returns a vector with them.
+func DecodeElements(r io.Reader) (*Vector, error) {
+	dec := gob.NewDecoder(r)
+	b := newBuilder(0)
+	for {
+		var elem interface{}
+		if err := dec.Decode(&elem); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		b.append(elem)
+	}
+	return b.vector(), nil
+}
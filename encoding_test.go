@@ -0,0 +1,39 @@
+package vector
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeElements(t *testing.T) {
+	require := require.New(t)
+
+	v := New(1, "foo", 3.5, nil, true, []byte("bar"))
+
+	var buf bytes.Buffer
+	require.NoError(v.EncodeElements(&buf))
+
+	result, err := DecodeElements(&buf)
+	require.NoError(err)
+	require.True(Equal(v, result))
+
+	v = makeVector(1000).Drop(10)
+	r, w, err := os.Pipe()
+	require.NoError(err)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- v.EncodeElements(w)
+		w.Close()
+	}()
+
+	result, err = DecodeElements(r)
+	require.NoError(err)
+	require.NoError(<-errs)
+	require.True(Equal(v, result))
+
+	_, err = DecodeElements(bytes.NewReader([]byte("not gob")))
+	require.Error(err)
+}
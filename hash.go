@@ -0,0 +1,84 @@
+package vector
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io"
+)
+
+// fnvOffset64 and fnvPrime64 are the parameters of the 64-bit FNV-1a hash.
+const (
+	fnvOffset64 = 14695981039346656037
+	fnvPrime64  = 1099511628211
+)
+
+// Canonical returns a vector with the same elements as the vector and the
+// canonical structure for its number of elements. Vectors that were dropped
+// are rebuilt without the elements they no longer hold, so two vectors with
+// the same elements have the same canonical form regardless of the operations
+// used to create them.
+func (v *Vector) Canonical() *Vector {
+	if v.start == 0 {
+		return v
+	}
+	return fromSlice(v.Slice())
+}
+
+// Hash returns a hash of the elements of the vector, suitable to be used as a
+// cache key. Elements are hashed using their type and Go-syntax representation,
+// and nested vectors are hashed by their elements, so vectors with the same
+// elements have the same hash. Since elements like pointers are represented by
+// their address, hashes are only meant to be compared within the same process.
+// Computing the hash takes O(n) time. The hash is not cached, so hashing a
+// vector does not change it in any observable way, not even for
+// reflect.DeepEqual.
+func (v *Vector) Hash() uint64 {
+	return hashElement(v)
+}
+
+// PrefixHashes returns the hash of every prefix of the vector, that is, the
+// i-th hash is computed from the hashes of the elements up to and including
+// the i-th one, as given by h. If h is nil, elements are hashed in the same way
+// as in Hash. Two vectors have the same prefix hashes up to the first element
+// in which they differ, so it's cheap to compare prefixes of vectors as they
+// grow, although, as with any hash, different prefixes may rarely collide.
+func (v *Vector) PrefixHashes(h func(interface{}) uint64) []uint64 {
+	if h == nil {
+		h = hashElement
+	}
+
+	result := make([]uint64, v.Count())
+	acc := uint64(fnvOffset64)
+	_ = v.each(0, v.Count(), func(i int, elem interface{}) error {
+		acc = (acc ^ h(elem)) * fnvPrime64
+		result[i] = acc
+		return nil
+	})
+	return result
+}
+
+// hashElement returns the hash of a single element, computed like Hash.
+func hashElement(elem interface{}) uint64 {
+	h := fnv.New64a()
+	writeElement(h, elem)
+	return h.Sum64()
+}
+
+// writeElement writes a representation of the given element to w that
+// identifies both its type and value.
+func writeElement(w io.Writer, elem interface{}) {
+	if v, ok := elem.(*Vector); ok {
+		_, _ = io.WriteString(w, "*vector.Vector[")
+		_ = v.each(0, v.Count(), func(i int, elem interface{}) error {
+			if i > 0 {
+				_, _ = io.WriteString(w, ",")
+			}
+			writeElement(w, elem)
+			return nil
+		})
+		_, _ = io.WriteString(w, "]")
+		return
+	}
+
+	_, _ = fmt.Fprintf(w, "%T(%#v)", elem, elem)
+}
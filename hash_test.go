@@ -0,0 +1,81 @@
+package vector
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanonical(t *testing.T) {
+	require := require.New(t)
+
+	v := makeVector(100)
+	require.True(v == v.Canonical())
+
+	dropped := v.Drop(40).Canonical()
+	require.Equal(0, dropped.start)
+	require.Equal(60, dropped.Count())
+	require.NoError(dropped.Validate())
+	require.True(Equal(v.Drop(40), dropped))
+	require.Equal(New(v.Drop(40).Slice()...), dropped)
+}
+
+func TestHash(t *testing.T) {
+	require := require.New(t)
+
+	v := New(1, 2, 3, 4, 5)
+	require.Equal(New(3, 4, 5).Hash(), v.Drop(2).Canonical().Hash())
+	require.Equal(New(3, 4, 5).Hash(), v.Drop(2).Hash())
+	require.Equal(New(1, New(2, 3)).Hash(), New(1, New(1, 2, 3).Drop(1)).Hash())
+	require.Equal(New().Hash(), New(1).Tail().Hash())
+
+	var empty *Vector
+	require.Equal(New().Hash(), empty.Hash())
+
+	require.NotEqual(New(1, 2, 3).Hash(), New(1, 2, 4).Hash())
+	require.NotEqual(New(1, 2).Hash(), New(1, 2, 3).Hash())
+	require.NotEqual(New(1).Hash(), New(int64(1)).Hash())
+	require.NotEqual(New("a,b").Hash(), New("a", "b").Hash())
+	require.NotEqual(New(New(1, 2)).Hash(), New(1, 2).Hash())
+
+	big := makeVector(2000)
+	hash := big.Hash()
+	require.Equal(hash, big.Hash())
+	require.Equal(hash, New(-1).AppendVector(big).Drop(1).Hash())
+	require.NotEqual(hash, big.Set(1500, -1).Hash())
+
+	// The levels of a trie higher than needed don't change the hash.
+	small := makeVector(100)
+	root := &node{make([]interface{}, vectorWidth)}
+	root.values[0] = small.root
+	tall := &Vector{100, 2 * uint(vectorBits), root, small.tail, 0}
+	require.NoError(tall.Validate())
+	require.Equal(small.Hash(), tall.Hash())
+
+	// Hashing doesn't leave anything behind that reflect.DeepEqual can see.
+	a, b := New(1, 2), New(1, 2)
+	a.Hash()
+	require.Equal(b, a)
+}
+
+func TestPrefixHashes(t *testing.T) {
+	require := require.New(t)
+
+	a := New(1, 2, 3, 4, 5).PrefixHashes(nil)
+	b := New(1, 2, 3, 9, 5).PrefixHashes(nil)
+	require.Len(a, 5)
+	require.Equal(a[:3], b[:3])
+	require.NotEqual(a[3], b[3])
+	require.NotEqual(a[4], b[4])
+
+	prefix := New(1, 2, 3).PrefixHashes(nil)
+	require.Equal(a[:3], prefix)
+	require.Equal(a, New(0, 1, 2, 3, 4, 5).Drop(1).PrefixHashes(nil))
+	require.NotEqual(a[0], a[1])
+
+	identity := func(x interface{}) uint64 {
+		return uint64(x.(int))
+	}
+	require.Equal(New(1, 2).PrefixHashes(identity), New(1, 2, 3).PrefixHashes(identity)[:2])
+	require.Empty(New().PrefixHashes(nil))
+}
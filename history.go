@@ -0,0 +1,61 @@
+package vector
+
+// History keeps track of the versions of a vector so changes made to it can
+// be undone and redone. Because vectors are persistent, each version shares
+// most of its structure with the others, so keeping them is cheap.
+type History struct {
+	limit   int
+	current *Vector
+	undo    []*Vector
+	redo    []*Vector
+}
+
+// NewHistory returns a new history starting at the given vector that keeps
+// at most limit versions to undo. If limit is less or equal than 0, there is
+// no limit.
+func NewHistory(v *Vector, limit int) *History {
+	return &History{limit: limit, current: v}
+}
+
+// Vector returns the current version of the vector.
+func (h *History) Vector() *Vector {
+	return h.current
+}
+
+// Do replaces the current version of the vector with the result of the given
+// function and returns it. Versions that were undone can no longer be redone.
+func (h *History) Do(f func(*Vector) *Vector) *Vector {
+	h.undo = append(h.undo, h.current)
+	if h.limit > 0 && len(h.undo) > h.limit {
+		h.undo = append(h.undo[:0], h.undo[len(h.undo)-h.limit:]...)
+	}
+	h.redo = nil
+	h.current = f(h.current)
+	return h.current
+}
+
+// Undo goes back to the previous version of the vector and reports whether
+// there was any version to go back to.
+func (h *History) Undo() bool {
+	if len(h.undo) == 0 {
+		return false
+	}
+
+	h.redo = append(h.redo, h.current)
+	h.current = h.undo[len(h.undo)-1]
+	h.undo = h.undo[:len(h.undo)-1]
+	return true
+}
+
+// Redo goes forward to the last undone version of the vector and reports
+// whether there was any version to go forward to.
+func (h *History) Redo() bool {
+	if len(h.redo) == 0 {
+		return false
+	}
+
+	h.undo = append(h.undo, h.current)
+	h.current = h.redo[len(h.redo)-1]
+	h.redo = h.redo[:len(h.redo)-1]
+	return true
+}
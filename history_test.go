@@ -0,0 +1,49 @@
+package vector
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistory(t *testing.T) {
+	require := require.New(t)
+
+	h := NewHistory(New(1, 2, 3), 0)
+	require.False(h.Undo())
+	require.False(h.Redo())
+
+	h.Do(func(v *Vector) *Vector { return v.Append(4) })
+	h.Do(func(v *Vector) *Vector { return v.Set(0, -1) })
+	require.True(Equal(New(-1, 2, 3, 4), h.Vector()))
+
+	require.True(h.Undo())
+	require.True(Equal(New(1, 2, 3, 4), h.Vector()))
+	require.True(h.Undo())
+	require.True(Equal(New(1, 2, 3), h.Vector()))
+	require.False(h.Undo())
+
+	require.True(h.Redo())
+	require.True(Equal(New(1, 2, 3, 4), h.Vector()))
+
+	h.Do(func(v *Vector) *Vector { return v.Drop(1) })
+	require.True(Equal(New(2, 3, 4), h.Vector()))
+	require.False(h.Redo())
+
+	require.True(h.Undo())
+	require.True(Equal(New(1, 2, 3, 4), h.Vector()))
+}
+
+func TestHistoryLimit(t *testing.T) {
+	require := require.New(t)
+
+	h := NewHistory(New(), 2)
+	for i := 0; i < 5; i++ {
+		h.Do(func(v *Vector) *Vector { return v.Append(i) })
+	}
+
+	require.True(h.Undo())
+	require.True(h.Undo())
+	require.False(h.Undo())
+	require.True(Equal(New(0, 1, 2), h.Vector()))
+}
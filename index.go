@@ -0,0 +1,45 @@
+package vector
+
+// Index maps every element of a vector to the position of its first
+// occurrence, so membership and position lookups take constant time instead of
+// a scan of the whole vector.
+//
+// Since vectors are immutable, an index never needs to be invalidated, but it
+// only describes the vector it was built from and not the vectors derived from
+// it with Append, Set or any other operation.
+type Index struct {
+	positions map[interface{}]int
+}
+
+// Index returns a new index of the elements of the vector. All elements must
+// be comparable, otherwise Index panics.
+func (v *Vector) Index() *Index {
+	positions := make(map[interface{}]int, v.Count())
+	_ = v.each(0, v.Count(), func(i int, elem interface{}) error {
+		if _, ok := positions[elem]; !ok {
+			positions[elem] = i
+		}
+		return nil
+	})
+	return &Index{positions}
+}
+
+// Contains returns whether the indexed vector contains the given element.
+func (idx *Index) Contains(elem interface{}) bool {
+	_, ok := idx.positions[elem]
+	return ok
+}
+
+// IndexOf returns the position of the first occurrence of the given element in
+// the indexed vector, or -1 if it's not in the vector.
+func (idx *Index) IndexOf(elem interface{}) int {
+	if i, ok := idx.positions[elem]; ok {
+		return i
+	}
+	return -1
+}
+
+// Len returns the number of distinct elements in the indexed vector.
+func (idx *Index) Len() int {
+	return len(idx.positions)
+}
@@ -0,0 +1,34 @@
+package vector
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndex(t *testing.T) {
+	require := require.New(t)
+
+	idx := New("a", "b", "a", 3, nil).Index()
+	require.Equal(4, idx.Len())
+	require.True(idx.Contains("a"))
+	require.True(idx.Contains(3))
+	require.True(idx.Contains(nil))
+	require.False(idx.Contains("c"))
+	require.False(idx.Contains(int64(3)))
+
+	require.Equal(0, idx.IndexOf("a"))
+	require.Equal(1, idx.IndexOf("b"))
+	require.Equal(3, idx.IndexOf(3))
+	require.Equal(-1, idx.IndexOf("c"))
+
+	idx = makeVector(2000).Drop(500).Index()
+	require.Equal(1500, idx.Len())
+	require.Equal(0, idx.IndexOf(500))
+	require.Equal(1499, idx.IndexOf(1999))
+	require.Equal(-1, idx.IndexOf(499))
+
+	require.Panics(func() {
+		New([]int{1}).Index()
+	})
+}
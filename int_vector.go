@@ -0,0 +1,213 @@
+package vector
+
+import "fmt"
+
+// IntVector is a persistent bit-partitioned vector trie of int64 elements.
+// It has the same structure as Vector, but its leaves store the elements
+// directly, without boxing them in interface{} values, which makes it faster
+// and lighter for numeric workloads.
+type IntVector struct {
+	count uint64
+	shift uint
+	root  *intNode
+	tail  *intNode
+}
+
+// NewInt returns a new int vector containing the given elements.
+func NewInt(elems ...int64) *IntVector {
+	v := emptyIntVector
+	for _, e := range elems {
+		v = v.Append(e)
+	}
+	return v
+}
+
+// Append returns a new int vector appending the element at the end of the
+// vector.
+func (v *IntVector) Append(elem int64) *IntVector {
+	if v.count-v.tailOffset() < uint64(vectorWidth) {
+		lenTail := len(v.tail.values)
+		tail := &intNode{values: make([]int64, lenTail+1)}
+		copy(tail.values, v.tail.values)
+		tail.values[lenTail] = elem
+		return &IntVector{v.count + 1, v.shift, v.root, tail}
+	}
+
+	var root *intNode
+	shift := v.shift
+	if (v.count >> vectorBits) > (1 << v.shift) {
+		root = &intNode{children: make([]*intNode, vectorWidth)}
+		root.children[0] = v.root
+		root.children[1] = newIntPath(v.shift, v.tail)
+		shift += uint(vectorBits)
+	} else {
+		root = v.pushTail(shift, v.root, v.tail)
+	}
+
+	tail := &intNode{values: []int64{elem}}
+	return &IntVector{v.count + 1, shift, root, tail}
+}
+
+// Get returns the element at the given position. If the position is negative,
+// returns elements in reverse order. If the element cannot be found in the
+// vector, it will return 0.
+func (v *IntVector) Get(i int) int64 {
+	var key = uint64(i)
+	if i < 0 {
+		key = v.count + uint64(i)
+	}
+
+	if key >= v.count {
+		return 0
+	}
+
+	return v.leafFor(key).values[key&uint64(vectorMask)]
+}
+
+// Set will change the value of the element at the given index. If the element
+// does not exist it will panic.
+func (v *IntVector) Set(i int, elem int64) *IntVector {
+	var key = uint64(i)
+	if i < 0 {
+		key = v.count + uint64(i)
+	}
+
+	if key >= v.count {
+		panic(fmt.Errorf("vector: index out of bounds, tried to get "+
+			"element %d of a vector with %d elements", key, v.count))
+	}
+
+	if key >= v.tailOffset() {
+		newTail := v.tail.clone()
+		newTail.values[key&uint64(vectorMask)] = elem
+		return &IntVector{v.count, v.shift, v.root, newTail}
+	}
+
+	root := v.root.clone()
+	n := root
+	for lvl := v.shift; lvl > 0; lvl -= uint(vectorBits) {
+		idx := (key >> lvl) & uint64(vectorMask)
+		newNode := n.children[idx].clone()
+		n.children[idx] = newNode
+		n = newNode
+	}
+
+	n.values[key&uint64(vectorMask)] = elem
+	return &IntVector{v.count, v.shift, root, v.tail}
+}
+
+// Count returns the number of elements in the vector.
+func (v *IntVector) Count() int {
+	return int(v.count)
+}
+
+// Sum returns the sum of all the elements in the vector.
+func (v *IntVector) Sum() int64 {
+	var sum int64
+	for key := uint64(0); key < v.count; key += uint64(vectorWidth) {
+		for _, x := range v.leafFor(key).values {
+			sum += x
+		}
+	}
+	return sum
+}
+
+// Map returns a new int vector with the elements of the current vector after
+// applying the given map function.
+func (v *IntVector) Map(f func(int64) int64) *IntVector {
+	result := NewInt()
+	for key := uint64(0); key < v.count; key += uint64(vectorWidth) {
+		for _, x := range v.leafFor(key).values {
+			result = result.Append(f(x))
+		}
+	}
+	return result
+}
+
+// Slice returns the elements of the vector in a slice.
+func (v *IntVector) Slice() []int64 {
+	result := make([]int64, 0, v.count)
+	for key := uint64(0); key < v.count; key += uint64(vectorWidth) {
+		result = append(result, v.leafFor(key).values...)
+	}
+	return result
+}
+
+// leafFor returns the leaf node containing the element with the given key,
+// which may be the tail.
+func (v *IntVector) leafFor(key uint64) *intNode {
+	if key >= v.tailOffset() {
+		return v.tail
+	}
+
+	n := v.root
+	for lvl := v.shift; lvl > 0; lvl -= uint(vectorBits) {
+		n = n.children[(key>>lvl)&uint64(vectorMask)]
+	}
+	return n
+}
+
+// pushTail pushes the tail to the rightmost node available and returns a new root.
+func (v *IntVector) pushTail(shift uint, root, tail *intNode) *intNode {
+	newRoot := root.clone()
+	newNode := tail
+	idx := ((v.count - 1) >> shift) & uint64(vectorMask)
+	if shift > uint(vectorBits) {
+		shift -= uint(vectorBits)
+		if n := root.children[idx]; n != nil {
+			newNode = v.pushTail(shift, n, tail)
+		} else {
+			newNode = newIntPath(shift, tail)
+		}
+	}
+
+	newRoot.children[idx] = newNode
+	return newRoot
+}
+
+// tailOffset returns the offset of elements that are not on the tail.
+func (v *IntVector) tailOffset() uint64 {
+	if v.count < uint64(vectorWidth) {
+		return 0
+	}
+	return ((v.count - 1) >> vectorBits) << vectorBits
+}
+
+// intNode is a node of an int vector. Inner nodes only have children and
+// leaves only have values.
+type intNode struct {
+	children []*intNode
+	values   []int64
+}
+
+func (n *intNode) clone() *intNode {
+	newNode := &intNode{}
+	if n.children != nil {
+		newNode.children = make([]*intNode, len(n.children))
+		copy(newNode.children, n.children)
+	}
+	if n.values != nil {
+		newNode.values = make([]int64, len(n.values))
+		copy(newNode.values, n.values)
+	}
+	return newNode
+}
+
+var emptyIntVector = &IntVector{
+	0,
+	5,
+	&intNode{children: make([]*intNode, vectorWidth)},
+	&intNode{},
+}
+
+// newIntPath creates a new path all the way through a branch from the given
+// level inserting at the leftmost leaf.
+func newIntPath(shift uint, n *intNode) *intNode {
+	if shift == 0 {
+		return n
+	}
+
+	node := &intNode{children: make([]*intNode, vectorWidth)}
+	node.children[0] = newIntPath(shift-uint(vectorBits), n)
+	return node
+}
@@ -0,0 +1,85 @@
+package vector
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntVectorAppendAndGet(t *testing.T) {
+	const n = 1<<15 + 1<<10 + 100
+	v := makeIntVector(n)
+	require.Equal(t, n, v.Count())
+	for i := 0; i < n; i++ {
+		if v.Get(i) != int64(i) {
+			require.Equal(t, int64(i), v.Get(i))
+		}
+	}
+
+	require.Equal(t, int64(n-1), v.Get(-1))
+	require.Equal(t, int64(0), v.Get(n))
+}
+
+func TestIntVectorSet(t *testing.T) {
+	require := require.New(t)
+
+	v := NewInt(1, 2, 3, 4, 5).
+		Set(0, -1).
+		Set(-1, -5)
+	require.Equal([]int64{-1, 2, 3, 4, -5}, v.Slice())
+
+	big := makeIntVector(2000)
+	require.Equal(int64(-1), big.Set(10, -1).Get(10))
+	require.Equal(int64(10), big.Get(10))
+
+	require.Panics(func() {
+		NewInt().Set(0, 1)
+	})
+}
+
+func TestIntVectorSum(t *testing.T) {
+	require.Equal(t, int64(0), NewInt().Sum())
+	require.Equal(t, int64(6), NewInt(1, 2, 3).Sum())
+	require.Equal(t, int64(999*1000/2), makeIntVector(1000).Sum())
+}
+
+func TestIntVectorMap(t *testing.T) {
+	v := makeIntVector(100).Map(func(x int64) int64 {
+		return x * 2
+	})
+
+	require.Equal(t, 100, v.Count())
+	for i := 0; i < 100; i++ {
+		require.Equal(t, int64(i*2), v.Get(i))
+	}
+}
+
+func BenchmarkSum(b *testing.B) {
+	const n = 10000
+
+	b.Run("boxed", func(b *testing.B) {
+		v := makeVector(n)
+		for i := 0; i < b.N; i++ {
+			var sum int
+			_ = v.Range(func(x interface{}) error {
+				sum += x.(int)
+				return nil
+			})
+		}
+	})
+
+	b.Run("int", func(b *testing.B) {
+		v := makeIntVector(n)
+		for i := 0; i < b.N; i++ {
+			_ = v.Sum()
+		}
+	})
+}
+
+func makeIntVector(len int) *IntVector {
+	v := NewInt()
+	for i := 0; i < len; i++ {
+		v = v.Append(int64(i))
+	}
+	return v
+}
@@ -0,0 +1,43 @@
+//go:build go1.23
+// +build go1.23
+
+package vector
+
+import "iter"
+
+// All returns an iterator over the logical indexes and elements of the vector.
+//
+//	for i, x := range v.All() {
+//		// do something with i and x
+//	}
+func (v *Vector) All() iter.Seq2[int, interface{}] {
+	return func(yield func(int, interface{}) bool) {
+		_ = v.each(0, v.Count(), func(i int, elem interface{}) error {
+			if !yield(i, elem) {
+				return ErrStop
+			}
+			return nil
+		})
+	}
+}
+
+// Values returns an iterator over the elements of the vector.
+func (v *Vector) Values() iter.Seq[interface{}] {
+	return func(yield func(interface{}) bool) {
+		_ = v.each(0, v.Count(), func(_ int, elem interface{}) error {
+			if !yield(elem) {
+				return ErrStop
+			}
+			return nil
+		})
+	}
+}
+
+// Collect returns a new vector with all the elements of the given iterator.
+func Collect(seq iter.Seq[interface{}]) *Vector {
+	b := newBuilder(0)
+	for elem := range seq {
+		b.append(elem)
+	}
+	return b.vector()
+}
@@ -0,0 +1,71 @@
+//go:build go1.23
+// +build go1.23
+
+package vector
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAll(t *testing.T) {
+	require := require.New(t)
+
+	v := makeVector(100).Drop(10)
+	var indexes, elems []interface{}
+	for i, x := range v.All() {
+		indexes = append(indexes, i)
+		elems = append(elems, x)
+	}
+	require.Equal(makeVector(90).Slice(), indexes)
+	require.Equal(v.Slice(), elems)
+
+	elems = nil
+	for i, x := range v.All() {
+		if i == 3 {
+			break
+		}
+		elems = append(elems, x)
+	}
+	require.Equal([]interface{}{10, 11, 12}, elems)
+}
+
+func TestValues(t *testing.T) {
+	require := require.New(t)
+
+	v := New(1, 2, 3, 4, 5)
+	var elems []interface{}
+	for x := range v.Values() {
+		elems = append(elems, x)
+		if x == 3 {
+			break
+		}
+	}
+	require.Equal([]interface{}{1, 2, 3}, elems)
+
+	var nilVector *Vector
+	for range nilVector.Values() {
+		t.Fatal("nil vector should not have values")
+	}
+}
+
+func TestCollect(t *testing.T) {
+	require := require.New(t)
+
+	v := makeVector(100)
+	require.True(Equal(v, Collect(v.Values())))
+
+	even := func(yield func(interface{}) bool) {
+		for x := range v.Values() {
+			if x.(int)%2 == 0 && !yield(x) {
+				return
+			}
+		}
+	}
+	require.True(Equal(v.Filter(func(x interface{}) bool {
+		return x.(int)%2 == 0
+	}), Collect(even)))
+
+	require.Equal(0, Collect(New().Values()).Count())
+}
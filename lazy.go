@@ -0,0 +1,79 @@
+package vector
+
+import "sync"
+
+// LazyVector is a vector with a fixed number of elements that are computed the
+// first time they are accessed and cached for the following accesses.
+//
+// Compared to building a vector with all the elements up front, a lazy vector
+// avoids computing elements that are never accessed, at the cost of taking a
+// lock on every access. Unlike Vector, a LazyVector is safe for concurrent use.
+type LazyVector struct {
+	mu       sync.Mutex
+	f        func(i int) interface{}
+	values   *Vector
+	computed []bool
+}
+
+// Lazy returns a new lazy vector of n elements whose values are computed with
+// the given function. The function is called at most once for every index and
+// it's called while holding the lock of the lazy vector, so it must not access
+// the lazy vector itself.
+func Lazy(n int, f func(i int) interface{}) *LazyVector {
+	if n < 0 {
+		panic("cannot create a lazy vector with less than 0 items")
+	}
+
+	return &LazyVector{
+		f:        f,
+		values:   fromSlice(make([]interface{}, n)),
+		computed: make([]bool, n),
+	}
+}
+
+// Get returns the element at the given position, computing it if it has not
+// been accessed before. If the position is negative, returns elements in
+// reverse order. If the position is out of bounds, it will return nil.
+func (v *LazyVector) Get(i int) interface{} {
+	n := len(v.computed)
+	if i < 0 {
+		i += n
+	}
+
+	if i < 0 || i >= n {
+		return nil
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.get(i)
+}
+
+// Count returns the number of elements in the vector.
+func (v *LazyVector) Count() int {
+	return len(v.computed)
+}
+
+// Vector returns a persistent vector with all the elements of the lazy
+// vector, computing the ones that have not been accessed yet.
+func (v *LazyVector) Vector() *Vector {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for i := range v.computed {
+		v.get(i)
+	}
+	return v.values
+}
+
+// get returns the element at the given position, which must be valid. The
+// lock must be held by the caller.
+func (v *LazyVector) get(i int) interface{} {
+	if v.computed[i] {
+		return v.values.Get(i)
+	}
+
+	elem := v.f(i)
+	v.values = v.values.Set(i, elem)
+	v.computed[i] = true
+	return elem
+}
@@ -0,0 +1,63 @@
+package vector
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLazy(t *testing.T) {
+	require := require.New(t)
+
+	const n = 100
+	var mu sync.Mutex
+	calls := make(map[int]int)
+	v := Lazy(n, func(i int) interface{} {
+		mu.Lock()
+		calls[i]++
+		mu.Unlock()
+		return i * i
+	})
+
+	require.Equal(n, v.Count())
+	require.Equal(25, v.Get(5))
+	require.Equal(99*99, v.Get(-1))
+	require.Nil(v.Get(n))
+	require.Len(calls, 2)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 10; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < n; i++ {
+				if v.Get(i) != i*i {
+					t.Errorf("unexpected value for element %d", i)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.Len(calls, n)
+	for i := 0; i < n; i++ {
+		require.Equal(1, calls[i], "element %d", i)
+	}
+
+	require.True(Equal(makeVector(n).Map(func(x interface{}) interface{} {
+		return x.(int) * x.(int)
+	}), v.Vector()))
+}
+
+func TestLazyVector(t *testing.T) {
+	var calls int
+	v := Lazy(3, func(i int) interface{} {
+		calls++
+		return i
+	})
+
+	require.True(t, Equal(New(0, 1, 2), v.Vector()))
+	require.True(t, Equal(New(0, 1, 2), v.Vector()))
+	require.Equal(t, 3, calls)
+}
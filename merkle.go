@@ -0,0 +1,203 @@
+package vector
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// MerkleRoot returns the root of a Merkle tree over the elements of the vector,
+// using h to hash every node. Every 32 consecutive elements, starting at the
+// first one, are hashed together into a leaf, and every 32 consecutive nodes of
+// a level into a node of the level above, until a single node is left. The
+// grouping only depends on the position of the elements, not on how the
+// vector is laid out in memory, so equal vectors have the same root even if
+// one of them was dropped. Elements are hashed by their type and Go-syntax
+// representation, as in Hash, and a change in an element only changes the
+// hashes in the path from its leaf to the root. The tree is not built over the
+// nodes of the trie and nothing is cached, so the whole tree is hashed again
+// on every call.
+func (v *Vector) MerkleRoot(h func([]byte) []byte) []byte {
+	return v.merkleHash(v.merkleHeight(), 0, h)
+}
+
+// MerkleProof is the proof that an element is part of a vector with a given
+// Merkle root, as returned by MerkleRoot. The tree is positional: its leaves
+// hold 32 consecutive elements each, counting from the first one, regardless
+// of the nodes of the trie, and it's hashed again every time a proof is made.
+type MerkleProof struct {
+	// Leaf holds the elements in the same leaf as the proven element.
+	Leaf []interface{}
+	// Offset is the position of the proven element in Leaf.
+	Offset int
+	// Levels holds the nodes in the path from the leaf to the root, one level
+	// at a time starting at the leaves.
+	Levels []MerkleLevel
+}
+
+// MerkleLevel is a level of the path of a MerkleProof.
+type MerkleLevel struct {
+	// Hashes holds the hashes of the nodes that are hashed together in the
+	// next level, which include the node in the path.
+	Hashes [][]byte
+	// Position is the position of the node in the path in Hashes.
+	Position int
+}
+
+// MerkleProof returns the proof that the element at the given index is part
+// of the vector, using h to hash every node. Computing the siblings of the
+// nodes in the path from the element to the root hashes the whole tree, so it
+// takes as long as MerkleRoot, but only the hashes in the proof are kept in
+// memory. If the element does not exist it will panic.
+func (v *Vector) MerkleProof(i int, h func([]byte) []byte) *MerkleProof {
+	n := v.Count()
+	if i < 0 || i >= n {
+		panic(fmt.Errorf("vector: index out of bounds, tried to get "+
+			"element %d of a vector with %d elements", i, n))
+	}
+
+	lo := i &^ int(vectorMask)
+	proof := &MerkleProof{
+		Leaf:   v.merkleElements(i >> vectorBits),
+		Offset: i - lo,
+	}
+
+	hash := merkleLeaf(proof.Leaf, h)
+	pos := i >> vectorBits
+	for level, height := 0, v.merkleHeight(); level < height; level++ {
+		start := pos &^ int(vectorMask)
+		end := start + int(vectorWidth)
+		if size := v.merkleSize(level); end > size {
+			end = size
+		}
+
+		hashes := make([][]byte, 0, end-start)
+		for j := start; j < end; j++ {
+			if j == pos {
+				hashes = append(hashes, hash)
+			} else {
+				hashes = append(hashes, v.merkleHash(level, j, h))
+			}
+		}
+
+		proof.Levels = append(proof.Levels, MerkleLevel{
+			Hashes:   hashes,
+			Position: pos - start,
+		})
+		hash = merkleNode(hashes, h)
+		pos >>= vectorBits
+	}
+	return proof
+}
+
+// Verify reports whether the proof shows that elem is part of a vector with
+// the given Merkle root, computed with h.
+func (p *MerkleProof) Verify(elem interface{}, root []byte, h func([]byte) []byte) bool {
+	if p.Offset < 0 || p.Offset >= len(p.Leaf) {
+		return false
+	}
+
+	if !bytes.Equal(elementBytes(elem), elementBytes(p.Leaf[p.Offset])) {
+		return false
+	}
+
+	hash := merkleLeaf(p.Leaf, h)
+	for _, level := range p.Levels {
+		if level.Position < 0 || level.Position >= len(level.Hashes) ||
+			!bytes.Equal(level.Hashes[level.Position], hash) {
+			return false
+		}
+		hash = merkleNode(level.Hashes, h)
+	}
+	return bytes.Equal(hash, root)
+}
+
+// merkleHeight returns the level of the root of the Merkle tree of the
+// vector, where the leaves are at level 0.
+func (v *Vector) merkleHeight() int {
+	var height int
+	for v.merkleSize(height) > 1 {
+		height++
+	}
+	return height
+}
+
+// merkleSize returns the number of nodes at the given level of the Merkle tree
+// of the vector. Even an empty vector has a leaf.
+func (v *Vector) merkleSize(level int) int {
+	size := (v.Count() + int(vectorMask)) >> vectorBits
+	for ; level > 0; level-- {
+		size = (size + int(vectorMask)) >> vectorBits
+	}
+	if size == 0 {
+		return 1
+	}
+	return size
+}
+
+// merkleHash returns the hash of the j-th node at the given level of the
+// Merkle tree of the vector.
+func (v *Vector) merkleHash(level, j int, h func([]byte) []byte) []byte {
+	if level == 0 {
+		return merkleLeaf(v.merkleElements(j), h)
+	}
+
+	start := j << vectorBits
+	end := start + int(vectorWidth)
+	if size := v.merkleSize(level - 1); end > size {
+		end = size
+	}
+
+	children := make([][]byte, 0, end-start)
+	for k := start; k < end; k++ {
+		children = append(children, v.merkleHash(level-1, k, h))
+	}
+	return merkleNode(children, h)
+}
+
+// merkleElements returns the elements of the j-th leaf of the Merkle tree of
+// the vector.
+func (v *Vector) merkleElements(j int) []interface{} {
+	lo := j << vectorBits
+	hi := lo + int(vectorWidth)
+	if n := v.Count(); hi > n {
+		hi = n
+	}
+	return v.elements(lo, hi)
+}
+
+// merkleLeaf returns the hash of a leaf with the given elements. Every element
+// is prefixed by the length of its representation, so that different elements
+// can never produce the same input for h.
+func merkleLeaf(elems []interface{}, h func([]byte) []byte) []byte {
+	buf := []byte{0}
+	for _, elem := range elems {
+		buf = appendPrefixed(buf, elementBytes(elem))
+	}
+	return h(buf)
+}
+
+// merkleNode returns the hash of a node with the given children hashes.
+func merkleNode(children [][]byte, h func([]byte) []byte) []byte {
+	buf := []byte{1}
+	for _, child := range children {
+		buf = appendPrefixed(buf, child)
+	}
+	return h(buf)
+}
+
+// appendPrefixed appends data to buf preceded by its length, encoded as a
+// varint.
+func appendPrefixed(buf, data []byte) []byte {
+	var size [binary.MaxVarintLen64]byte
+	buf = append(buf, size[:binary.PutUvarint(size[:], uint64(len(data)))]...)
+	return append(buf, data...)
+}
+
+// elementBytes returns the representation of an element written by
+// writeElement.
+func elementBytes(elem interface{}) []byte {
+	var buf bytes.Buffer
+	writeElement(&buf, elem)
+	return buf.Bytes()
+}
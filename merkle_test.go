@@ -0,0 +1,74 @@
+package vector
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func sha256Hash(data []byte) []byte {
+	sum := sha256.Sum256(data)
+	return sum[:]
+}
+
+func TestMerkleRoot(t *testing.T) {
+	require := require.New(t)
+
+	v := makeVector(2000)
+	root := v.MerkleRoot(sha256Hash)
+	require.Len(root, sha256.Size)
+	require.Equal(root, makeVector(2000).MerkleRoot(sha256Hash))
+	require.Equal(root, New(-1).AppendVector(v).Drop(1).MerkleRoot(sha256Hash))
+
+	require.NotEqual(root, v.Set(1500, -1).MerkleRoot(sha256Hash))
+	require.NotEqual(root, v.Append(2000).MerkleRoot(sha256Hash))
+	require.NotEqual(root, makeVector(1999).MerkleRoot(sha256Hash))
+	require.NotEqual(New(1).MerkleRoot(sha256Hash), New(int64(1)).MerkleRoot(sha256Hash))
+
+	require.Equal(New().MerkleRoot(sha256Hash), New(1).Tail().MerkleRoot(sha256Hash))
+	require.NotEqual(New().MerkleRoot(sha256Hash), New(nil).MerkleRoot(sha256Hash))
+}
+
+func TestMerkleProof(t *testing.T) {
+	require := require.New(t)
+
+	v := makeVector(2000)
+	root := v.MerkleRoot(sha256Hash)
+	for _, i := range []int{0, 31, 32, 1500, 1999} {
+		proof := v.MerkleProof(i, sha256Hash)
+		require.Equal(i, proof.Leaf[proof.Offset])
+		require.True(proof.Verify(i, root, sha256Hash), "element %d", i)
+		require.False(proof.Verify(-1, root, sha256Hash))
+	}
+
+	changed := v.Set(1500, -1)
+	changedRoot := changed.MerkleRoot(sha256Hash)
+	require.False(v.MerkleProof(1500, sha256Hash).Verify(1500, changedRoot, sha256Hash))
+	require.True(changed.MerkleProof(1500, sha256Hash).Verify(-1, changedRoot, sha256Hash))
+
+	// Element 10 is in the first group of 32 leaves and element 1500 in the
+	// second one, so only the hash of the second group changes.
+	before := v.MerkleProof(10, sha256Hash)
+	after := changed.MerkleProof(10, sha256Hash)
+	require.Len(after.Levels, 2)
+	require.Equal(before.Leaf, after.Leaf)
+	require.Equal(before.Levels[0], after.Levels[0])
+	require.Equal(before.Levels[1].Hashes[0], after.Levels[1].Hashes[0])
+	require.NotEqual(before.Levels[1].Hashes[1], after.Levels[1].Hashes[1])
+
+	big := makeVector(40000)
+	bigRoot := big.MerkleRoot(sha256Hash)
+	for _, i := range []int{0, 1023, 1024, 39999} {
+		proof := big.MerkleProof(i, sha256Hash)
+		require.Len(proof.Levels, 3)
+		require.True(proof.Verify(i, bigRoot, sha256Hash), "element %d", i)
+	}
+
+	proof := New("a").MerkleProof(0, sha256Hash)
+	require.Empty(proof.Levels)
+	require.True(proof.Verify("a", New("a").MerkleRoot(sha256Hash), sha256Hash))
+
+	require.Panics(func() { v.MerkleProof(2000, sha256Hash) })
+	require.Panics(func() { New().MerkleProof(0, sha256Hash) })
+}
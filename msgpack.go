@@ -0,0 +1,297 @@
+package vector
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// MarshalMsgpack returns the vector encoded as a msgpack array of its
+// elements. Supported elements are nil, booleans, integers, floats, strings,
+// byte slices and nested vectors, which are encoded as nested arrays.
+func (v *Vector) MarshalMsgpack() ([]byte, error) {
+	return appendMsgpack(nil, v)
+}
+
+// UnmarshalMsgpack replaces the vector with the elements of the given msgpack
+// array. Integers are decoded as int, unless they don't fit in an int, in
+// which case they are decoded as int64 or uint64. Nested arrays are decoded as
+// vectors. Maps and extension types are not supported.
+func (v *Vector) UnmarshalMsgpack(data []byte) error {
+	elem, rest, err := decodeMsgpack(data)
+	if err != nil {
+		return err
+	}
+
+	if len(rest) > 0 {
+		return fmt.Errorf("vector: %d unexpected bytes after msgpack array", len(rest))
+	}
+
+	result, ok := elem.(*Vector)
+	if !ok {
+		return fmt.Errorf("vector: msgpack data is not an array: %T", elem)
+	}
+
+	*v = *result
+	return nil
+}
+
+var errMsgpackEOF = errors.New("vector: unexpected end of msgpack data")
+
+func appendMsgpack(buf []byte, elem interface{}) ([]byte, error) {
+	switch x := elem.(type) {
+	case nil:
+		return append(buf, 0xc0), nil
+	case bool:
+		if x {
+			return append(buf, 0xc3), nil
+		}
+		return append(buf, 0xc2), nil
+	case int:
+		return appendMsgpackInt(buf, int64(x)), nil
+	case int8:
+		return appendMsgpackInt(buf, int64(x)), nil
+	case int16:
+		return appendMsgpackInt(buf, int64(x)), nil
+	case int32:
+		return appendMsgpackInt(buf, int64(x)), nil
+	case int64:
+		return appendMsgpackInt(buf, x), nil
+	case uint:
+		return appendMsgpackUint(buf, uint64(x)), nil
+	case uint8:
+		return appendMsgpackUint(buf, uint64(x)), nil
+	case uint16:
+		return appendMsgpackUint(buf, uint64(x)), nil
+	case uint32:
+		return appendMsgpackUint(buf, uint64(x)), nil
+	case uint64:
+		return appendMsgpackUint(buf, x), nil
+	case float32:
+		buf = append(buf, 0xca)
+		return appendUint32(buf, math.Float32bits(x)), nil
+	case float64:
+		buf = append(buf, 0xcb)
+		return appendUint64(buf, math.Float64bits(x)), nil
+	case string:
+		n := len(x)
+		switch {
+		case n < 32:
+			buf = append(buf, 0xa0|byte(n))
+		case n <= math.MaxUint8:
+			buf = append(buf, 0xd9, byte(n))
+		case n <= math.MaxUint16:
+			buf = appendUint16(append(buf, 0xda), uint16(n))
+		default:
+			buf = appendUint32(append(buf, 0xdb), uint32(n))
+		}
+		return append(buf, x...), nil
+	case []byte:
+		n := len(x)
+		switch {
+		case n <= math.MaxUint8:
+			buf = append(buf, 0xc4, byte(n))
+		case n <= math.MaxUint16:
+			buf = appendUint16(append(buf, 0xc5), uint16(n))
+		default:
+			buf = appendUint32(append(buf, 0xc6), uint32(n))
+		}
+		return append(buf, x...), nil
+	case *Vector:
+		n := x.Count()
+		switch {
+		case n < 16:
+			buf = append(buf, 0x90|byte(n))
+		case n <= math.MaxUint16:
+			buf = appendUint16(append(buf, 0xdc), uint16(n))
+		default:
+			buf = appendUint32(append(buf, 0xdd), uint32(n))
+		}
+
+		err := x.each(0, n, func(_ int, elem interface{}) error {
+			var err error
+			buf, err = appendMsgpack(buf, elem)
+			return err
+		})
+		return buf, err
+	default:
+		return nil, fmt.Errorf("vector: cannot encode element of type %T as msgpack", elem)
+	}
+}
+
+func appendMsgpackInt(buf []byte, x int64) []byte {
+	switch {
+	case x >= 0:
+		return appendMsgpackUint(buf, uint64(x))
+	case x >= -32:
+		return append(buf, byte(x))
+	case x >= math.MinInt8:
+		return append(buf, 0xd0, byte(x))
+	case x >= math.MinInt16:
+		return appendUint16(append(buf, 0xd1), uint16(x))
+	case x >= math.MinInt32:
+		return appendUint32(append(buf, 0xd2), uint32(x))
+	default:
+		return appendUint64(append(buf, 0xd3), uint64(x))
+	}
+}
+
+func appendMsgpackUint(buf []byte, x uint64) []byte {
+	switch {
+	case x < 128:
+		return append(buf, byte(x))
+	case x <= math.MaxUint8:
+		return append(buf, 0xcc, byte(x))
+	case x <= math.MaxUint16:
+		return appendUint16(append(buf, 0xcd), uint16(x))
+	case x <= math.MaxUint32:
+		return appendUint32(append(buf, 0xce), uint32(x))
+	default:
+		return appendUint64(append(buf, 0xcf), x)
+	}
+}
+
+func appendUint16(buf []byte, x uint16) []byte {
+	var b [2]byte
+	binary.BigEndian.PutUint16(b[:], x)
+	return append(buf, b[:]...)
+}
+
+func appendUint32(buf []byte, x uint32) []byte {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], x)
+	return append(buf, b[:]...)
+}
+
+func appendUint64(buf []byte, x uint64) []byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], x)
+	return append(buf, b[:]...)
+}
+
+// decodeMsgpack decodes the first msgpack value in data and returns it along
+// with the remaining data.
+func decodeMsgpack(data []byte) (interface{}, []byte, error) {
+	if len(data) == 0 {
+		return nil, nil, errMsgpackEOF
+	}
+
+	c, data := data[0], data[1:]
+	switch {
+	case c <= 0x7f:
+		return int(c), data, nil
+	case c >= 0xe0:
+		return int(int8(c)), data, nil
+	case c&0xe0 == 0xa0:
+		return decodeMsgpackString(data, int(c&0x1f))
+	case c&0xf0 == 0x90:
+		return decodeMsgpackArray(data, int(c&0x0f))
+	}
+
+	switch c {
+	case 0xc0:
+		return nil, data, nil
+	case 0xc2:
+		return false, data, nil
+	case 0xc3:
+		return true, data, nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		x, data, err := readUint(data, 1<<(c-0xcc))
+		if err != nil {
+			return nil, nil, err
+		}
+		if x <= math.MaxInt64 && int64(x) <= int64(maxInt) {
+			return int(x), data, nil
+		}
+		return x, data, nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (c - 0xd0)
+		x, data, err := readUint(data, size)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		shift := uint(64 - 8*size)
+		n := int64(x<<shift) >> shift
+		if n >= int64(minInt) && n <= int64(maxInt) {
+			return int(n), data, nil
+		}
+		return n, data, nil
+	case 0xca:
+		x, data, err := readUint(data, 4)
+		if err != nil {
+			return nil, nil, err
+		}
+		return math.Float32frombits(uint32(x)), data, nil
+	case 0xcb:
+		x, data, err := readUint(data, 8)
+		if err != nil {
+			return nil, nil, err
+		}
+		return math.Float64frombits(x), data, nil
+	case 0xd9, 0xda, 0xdb:
+		n, data, err := readUint(data, 1<<(c-0xd9))
+		if err != nil {
+			return nil, nil, err
+		}
+		return decodeMsgpackString(data, int(n))
+	case 0xc4, 0xc5, 0xc6:
+		n, data, err := readUint(data, 1<<(c-0xc4))
+		if err != nil {
+			return nil, nil, err
+		}
+		if uint64(len(data)) < n {
+			return nil, nil, errMsgpackEOF
+		}
+		b := make([]byte, n)
+		copy(b, data)
+		return b, data[n:], nil
+	case 0xdc, 0xdd:
+		n, data, err := readUint(data, 2<<(c-0xdc))
+		if err != nil {
+			return nil, nil, err
+		}
+		return decodeMsgpackArray(data, int(n))
+	default:
+		return nil, nil, fmt.Errorf("vector: unsupported msgpack type 0x%x", c)
+	}
+}
+
+func decodeMsgpackString(data []byte, n int) (interface{}, []byte, error) {
+	if len(data) < n {
+		return nil, nil, errMsgpackEOF
+	}
+	return string(data[:n]), data[n:], nil
+}
+
+func decodeMsgpackArray(data []byte, n int) (interface{}, []byte, error) {
+	b := newBuilder(0)
+	for i := 0; i < n; i++ {
+		var elem interface{}
+		var err error
+		elem, data, err = decodeMsgpack(data)
+		if err != nil {
+			return nil, nil, err
+		}
+		b.append(elem)
+	}
+	return b.vector(), data, nil
+}
+
+func readUint(data []byte, size int) (uint64, []byte, error) {
+	if len(data) < size {
+		return 0, nil, errMsgpackEOF
+	}
+
+	var x uint64
+	for _, b := range data[:size] {
+		x = x<<8 | uint64(b)
+	}
+	return x, data[size:], nil
+}
+
+const (
+	maxInt = int(^uint(0) >> 1)
+	minInt = -maxInt - 1
+)
@@ -0,0 +1,53 @@
+package vector
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMsgpack(t *testing.T) {
+	require := require.New(t)
+
+	v := New(
+		1, "a", -1, "foo", 0, 127, 128, -32, -33, 1<<16, -1<<40,
+		strings.Repeat("x", 300), nil, true, false, 1.5, float32(2.5),
+		[]byte("bar"), New(1, New("nested")),
+	)
+
+	data, err := v.MarshalMsgpack()
+	require.NoError(err)
+
+	result := New()
+	require.NoError(result.UnmarshalMsgpack(data))
+	require.True(Equal(v, result), "expected %s, got %s", v, result)
+
+	big := makeVector(70000).Drop(3)
+	data, err = big.MarshalMsgpack()
+	require.NoError(err)
+	require.NoError(result.UnmarshalMsgpack(data))
+	require.True(Equal(big, result))
+}
+
+func TestMsgpackEncoding(t *testing.T) {
+	require := require.New(t)
+
+	data, err := New(1, "a", nil, true, -1, 200).MarshalMsgpack()
+	require.NoError(err)
+	require.Equal([]byte{0x96, 0x01, 0xa1, 'a', 0xc0, 0xc3, 0xff, 0xcc, 200}, data)
+}
+
+func TestMsgpackErrors(t *testing.T) {
+	require := require.New(t)
+
+	_, err := New(struct{}{}).MarshalMsgpack()
+	require.Error(err)
+
+	v := New()
+	require.Error(v.UnmarshalMsgpack(nil))
+	require.Error(v.UnmarshalMsgpack([]byte{0x92, 0x01}))
+	require.Error(v.UnmarshalMsgpack([]byte{0x01}))
+	require.Error(v.UnmarshalMsgpack([]byte{0x91, 0x01, 0x02}))
+	require.Error(v.UnmarshalMsgpack([]byte{0x91, 0x80}))
+}
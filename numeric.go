@@ -0,0 +1,513 @@
+package vector
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// ErrLengthMismatch is returned by operations between two vectors that
+// require both of them to have the same number of elements.
+var ErrLengthMismatch = errors.New("vector: vectors have different lengths")
+
+// ErrEmpty is returned by operations that require the vector to have at
+// least one element.
+var ErrEmpty = errors.New("vector: vector is empty")
+
+// Add returns a new vector with the element-wise sum of the vector and other.
+// Elements must be numeric. The sum of two integers is an int, or an int64 if
+// any of them is an int64, and any other sum is a float64.
+func (v *Vector) Add(other *Vector) (*Vector, error) {
+	return v.combine(other, func(a, b int64) int64 {
+		return a + b
+	}, func(a, b float64) float64 {
+		return a + b
+	})
+}
+
+// Sub returns a new vector with the element-wise difference of the vector and
+// other. Elements must be numeric. The difference of two integers is an int,
+// or an int64 if any of them is an int64, and any other difference is a
+// float64.
+func (v *Vector) Sub(other *Vector) (*Vector, error) {
+	return v.combine(other, func(a, b int64) int64 {
+		return a - b
+	}, func(a, b float64) float64 {
+		return a - b
+	})
+}
+
+// Mul returns a new vector with the element-wise product of the vector and
+// other. Elements must be numeric. The product of two integers is an int, or
+// an int64 if any of them is an int64, and any other product is a float64.
+func (v *Vector) Mul(other *Vector) (*Vector, error) {
+	return v.combine(other, func(a, b int64) int64 {
+		return a * b
+	}, func(a, b float64) float64 {
+		return a * b
+	})
+}
+
+// Div returns a new vector with the element-wise quotient of the vector and
+// other. Elements must be numeric. Quotients are always float64, even for
+// integers.
+func (v *Vector) Div(other *Vector) (*Vector, error) {
+	return v.combine(other, nil, func(a, b float64) float64 {
+		return a / b
+	})
+}
+
+// Dot returns the dot product of the vector and other, that is, the sum of the
+// products of their elements at the same positions. Elements must be numeric.
+func (v *Vector) Dot(other *Vector) (float64, error) {
+	n := v.Count()
+	if n != other.Count() {
+		return 0, ErrLengthMismatch
+	}
+
+	var sum float64
+	for i := 0; i < n; i++ {
+		a, err := numericElement(i, v.Get(i))
+		if err != nil {
+			return 0, err
+		}
+
+		b, err := numericElement(i, other.Get(i))
+		if err != nil {
+			return 0, err
+		}
+
+		sum += a * b
+	}
+	return sum, nil
+}
+
+// Norm returns the euclidean norm of the vector. Elements must be numeric.
+func (v *Vector) Norm() (float64, error) {
+	var sum float64
+	err := v.each(0, v.Count(), func(i int, elem interface{}) error {
+		x, err := numericElement(i, elem)
+		sum += x * x
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return math.Sqrt(sum), nil
+}
+
+// Scale returns a new vector of float64 with every element of a numeric vector
+// multiplied by factor.
+func (v *Vector) Scale(factor float64) (*Vector, error) {
+	return v.mapFloats(func(x float64) float64 {
+		return x * factor
+	})
+}
+
+// Offset returns a new vector of float64 with delta added to every element of
+// a numeric vector.
+func (v *Vector) Offset(delta float64) (*Vector, error) {
+	return v.mapFloats(func(x float64) float64 {
+		return x + delta
+	})
+}
+
+// Clamp returns a new vector of float64 with every element of a numeric vector
+// limited to the range [lo, hi]. If lo is greater than hi, it will panic.
+func (v *Vector) Clamp(lo, hi float64) (*Vector, error) {
+	if lo > hi {
+		panic(fmt.Errorf("vector: cannot clamp to an empty range [%v, %v]", lo, hi))
+	}
+
+	return v.mapFloats(func(x float64) float64 {
+		return math.Max(lo, math.Min(hi, x))
+	})
+}
+
+// Normalize returns a new vector of float64 with the elements of a numeric
+// vector divided by its euclidean norm, so that the result has a norm of 1.
+// An error is returned if the norm is 0, which includes empty vectors.
+func (v *Vector) Normalize() (*Vector, error) {
+	norm, err := v.Norm()
+	if err != nil {
+		return nil, err
+	}
+
+	if norm == 0 {
+		return nil, errors.New("vector: cannot normalize a vector with a norm of 0")
+	}
+
+	return v.mapFloats(func(x float64) float64 {
+		return x / norm
+	})
+}
+
+// Mean returns the arithmetic mean of a numeric vector.
+func (v *Vector) Mean() (float64, error) {
+	mean, _, err := v.moments()
+	return mean, err
+}
+
+// Variance returns the population variance of a numeric vector, that is, the
+// mean of the squared differences between the elements and their mean.
+func (v *Vector) Variance() (float64, error) {
+	_, variance, err := v.moments()
+	return variance, err
+}
+
+// StdDev returns the population standard deviation of a numeric vector, which
+// is the square root of its variance.
+func (v *Vector) StdDev() (float64, error) {
+	_, variance, err := v.moments()
+	return math.Sqrt(variance), err
+}
+
+// moments returns the mean and population variance of a numeric vector,
+// computed in a single pass with Welford's algorithm, which does not suffer
+// from the loss of precision of subtracting two large sums.
+func (v *Vector) moments() (mean, variance float64, err error) {
+	n := v.Count()
+	if n == 0 {
+		return 0, 0, ErrEmpty
+	}
+
+	var m2 float64
+	err = v.each(0, n, func(i int, elem interface{}) error {
+		x, err := numericElement(i, elem)
+		if err != nil {
+			return err
+		}
+
+		delta := x - mean
+		mean += delta / float64(i+1)
+		m2 += delta * (x - mean)
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return mean, m2 / float64(n), nil
+}
+
+// CountInRange returns the number of elements of a numeric vector that are
+// between lo and hi, both included.
+func (v *Vector) CountInRange(lo, hi float64) (int, error) {
+	var n int
+	err := v.each(0, v.Count(), func(i int, elem interface{}) error {
+		x, err := numericElement(i, elem)
+		if err != nil {
+			return err
+		}
+
+		if lo <= x && x <= hi {
+			n++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// Percentile returns the p-th percentile of a numeric vector, with p between
+// 0 and 100, interpolating linearly between the two closest elements when the
+// percentile falls between them.
+func (v *Vector) Percentile(p float64) (float64, error) {
+	if p < 0 || p > 100 || math.IsNaN(p) {
+		return 0, fmt.Errorf("vector: percentile %v is not between 0 and 100", p)
+	}
+
+	n := v.Count()
+	if n == 0 {
+		return 0, ErrEmpty
+	}
+
+	elems := make([]interface{}, n)
+	err := v.each(0, n, func(i int, elem interface{}) error {
+		x, err := numericElement(i, elem)
+		elems[i] = x
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	less := func(a, b interface{}) bool {
+		return a.(float64) < b.(float64)
+	}
+
+	rank := p / 100 * float64(n-1)
+	lo := int(math.Floor(rank))
+	x := quickselect(elems, lo, less).(float64)
+	if lo == n-1 {
+		return x, nil
+	}
+
+	// After selecting lo, all elements after it are greater or equal, so the
+	// next order statistic is the smallest of them.
+	y := quickselect(elems[lo+1:], 0, less).(float64)
+	return x + (y-x)*(rank-float64(lo)), nil
+}
+
+// Median returns the median of a numeric vector, which is its 50th
+// percentile.
+func (v *Vector) Median() (float64, error) {
+	return v.Percentile(50)
+}
+
+// Convolve returns a new vector of float64 with the discrete convolution of a
+// numeric vector and the given kernel. Only the positions where the kernel
+// fully overlaps the vector are computed, so the result has
+// Count()-len(kernel)+1 elements, or none if the kernel is longer than the
+// vector. Convolve panics if the kernel is empty.
+func (v *Vector) Convolve(kernel []float64) (*Vector, error) {
+	if len(kernel) == 0 {
+		panic("vector: cannot convolve with an empty kernel")
+	}
+
+	xs, err := v.floats()
+	if err != nil {
+		return nil, err
+	}
+
+	b := newBuilder(len(xs) - len(kernel) + 1)
+	for i := 0; i+len(kernel) <= len(xs); i++ {
+		var sum float64
+		for j := range kernel {
+			sum += xs[i+j] * kernel[len(kernel)-1-j]
+		}
+		b.append(sum)
+	}
+	return b.vector(), nil
+}
+
+// DeltaEncode returns a new vector with the first element of a numeric vector
+// followed by the differences between every element and the previous one,
+// which are much smaller than the elements themselves for vectors that grow
+// steadily, such as timestamps or identifiers. Integers produce integers and
+// any other element produces a float64. DeltaDecode reverses it.
+func (v *Vector) DeltaEncode() (*Vector, error) {
+	b := newBuilder(v.Count())
+	var prev interface{} = 0
+	err := v.each(0, v.Count(), func(i int, elem interface{}) error {
+		delta, err := combineElements(i, elem, prev, func(a, b int64) int64 {
+			return a - b
+		}, func(a, b float64) float64 {
+			return a - b
+		})
+		if err != nil {
+			return err
+		}
+
+		b.append(delta)
+		prev = elem
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return b.vector(), nil
+}
+
+// DeltaDecode returns a new vector with the running totals of a numeric
+// vector encoded with DeltaEncode, which are the original elements.
+func (v *Vector) DeltaDecode() (*Vector, error) {
+	b := newBuilder(v.Count())
+	var acc interface{} = 0
+	err := v.each(0, v.Count(), func(i int, elem interface{}) error {
+		var err error
+		acc, err = combineElements(i, acc, elem, func(a, b int64) int64 {
+			return a + b
+		}, func(a, b float64) float64 {
+			return a + b
+		})
+		if err != nil {
+			return err
+		}
+
+		b.append(acc)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return b.vector(), nil
+}
+
+// EqualApprox returns whether a vector has the same items as another vector,
+// considering equal any two floats whose difference is at most eps. Elements
+// that are not floats are compared using reflect.DeepEqual.
+func EqualApprox(v1, v2 *Vector, eps float64) bool {
+	return EqualFunc(v1, v2, func(a, b interface{}) bool {
+		x, ok := toFloatOnly(a)
+		y, ok2 := toFloatOnly(b)
+		if ok && ok2 {
+			return math.Abs(x-y) <= eps
+		}
+		return reflect.DeepEqual(a, b)
+	})
+}
+
+// combine returns a new vector with the results of combining the elements of
+// both vectors at the same positions. If both elements are integers and ints
+// is not nil, they are combined with ints, otherwise they are combined as
+// floats with floats.
+func (v *Vector) combine(
+	other *Vector,
+	ints func(a, b int64) int64,
+	floats func(a, b float64) float64,
+) (*Vector, error) {
+	n := v.Count()
+	if n != other.Count() {
+		return nil, ErrLengthMismatch
+	}
+
+	result := newBuilder(n)
+	for i := 0; i < n; i++ {
+		elem, err := combineElements(i, v.Get(i), other.Get(i), ints, floats)
+		if err != nil {
+			return nil, err
+		}
+		result.append(elem)
+	}
+	return result.vector(), nil
+}
+
+// combineElements returns the result of combining the two elements at the
+// given index. If both are integers that fit in an int64 and ints is not nil,
+// they are combined with ints into an int64, if any of them is an int64, or an
+// int otherwise. Any other elements are combined with floats into a float64.
+func combineElements(
+	i int,
+	x, y interface{},
+	ints func(a, b int64) int64,
+	floats func(a, b float64) float64,
+) (interface{}, error) {
+	if ints != nil {
+		a, ok := toInt(x)
+		b, ok2 := toInt(y)
+		if ok && ok2 {
+			_, xIsInt64 := x.(int64)
+			_, yIsInt64 := y.(int64)
+			if xIsInt64 || yIsInt64 {
+				return ints(a, b), nil
+			}
+			return int(ints(a, b)), nil
+		}
+	}
+
+	a, err := numericElement(i, x)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := numericElement(i, y)
+	if err != nil {
+		return nil, err
+	}
+
+	return floats(a, b), nil
+}
+
+// mapFloats returns a new vector of float64 with the results of applying f to
+// every element of a numeric vector.
+func (v *Vector) mapFloats(f func(float64) float64) (*Vector, error) {
+	b := newBuilder(v.Count())
+	err := v.each(0, v.Count(), func(i int, elem interface{}) error {
+		x, err := numericElement(i, elem)
+		if err != nil {
+			return err
+		}
+
+		b.append(f(x))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return b.vector(), nil
+}
+
+// floats returns the elements of a numeric vector as float64.
+func (v *Vector) floats() ([]float64, error) {
+	xs := make([]float64, v.Count())
+	err := v.each(0, v.Count(), func(i int, elem interface{}) error {
+		x, err := numericElement(i, elem)
+		xs[i] = x
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return xs, nil
+}
+
+// numericElement returns the element at the given index as a float64 or an
+// error if it's not numeric.
+func numericElement(i int, elem interface{}) (float64, error) {
+	f, ok := toFloat(elem)
+	if !ok {
+		return 0, fmt.Errorf("vector: element %d is not numeric: %T", i, elem)
+	}
+	return f, nil
+}
+
+// toInt returns the given value as an int64 if it's an integer that fits in
+// an int64.
+func toInt(x interface{}) (int64, bool) {
+	switch x := x.(type) {
+	case int:
+		return int64(x), true
+	case int8:
+		return int64(x), true
+	case int16:
+		return int64(x), true
+	case int32:
+		return int64(x), true
+	case int64:
+		return x, true
+	case uint:
+		return int64(x), uint64(x) <= math.MaxInt64
+	case uint8:
+		return int64(x), true
+	case uint16:
+		return int64(x), true
+	case uint32:
+		return int64(x), true
+	case uint64:
+		return int64(x), x <= math.MaxInt64
+	default:
+		return 0, false
+	}
+}
+
+// toFloatOnly returns the given value as a float64 if it's a float.
+func toFloatOnly(x interface{}) (float64, bool) {
+	switch x := x.(type) {
+	case float64:
+		return x, true
+	case float32:
+		return float64(x), true
+	default:
+		return 0, false
+	}
+}
+
+// toFloat returns the given value as a float64 if it's an integer or a float.
+func toFloat(x interface{}) (float64, bool) {
+	switch x := x.(type) {
+	case float64:
+		return x, true
+	case float32:
+		return float64(x), true
+	case uint64:
+		return float64(x), true
+	case uint:
+		return float64(x), true
+	default:
+		n, ok := toInt(x)
+		return float64(n), ok
+	}
+}
@@ -0,0 +1,360 @@
+package vector
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdd(t *testing.T) {
+	require := require.New(t)
+
+	v, err := New(1, 2, 3).Add(New(10, 20, 30))
+	require.NoError(err)
+	require.True(Equal(New(11, 22, 33), v))
+
+	v, err = New(1, 2.5, int8(3)).Add(New(0.5, 1, uint(1)))
+	require.NoError(err)
+	require.True(Equal(New(1.5, 3.5, 4), v))
+
+	v, err = New(int64(1), 2).Add(New(int64(2), int64(3)))
+	require.NoError(err)
+	require.Equal([]interface{}{int64(3), int64(5)}, v.Slice())
+
+	// Unsigned integers that don't fit in an int64 are added as floats instead
+	// of wrapping around.
+	v, err = New(uint64(math.MaxUint64)).Add(New(1))
+	require.NoError(err)
+	require.Equal([]interface{}{float64(math.MaxUint64) + 1}, v.Slice())
+
+	_, err = New(1, 2, 3).Add(New(1, 2))
+	require.Equal(ErrLengthMismatch, err)
+
+	_, err = New(1, "a").Add(New(1, 2))
+	require.EqualError(err, "vector: element 1 is not numeric: string")
+}
+
+func TestSub(t *testing.T) {
+	require := require.New(t)
+
+	v, err := New(10, 20, 30).Sub(New(1, 2, 3.5))
+	require.NoError(err)
+	require.True(Equal(New(9, 18, 26.5), v))
+
+	_, err = New(1).Sub(New())
+	require.Equal(ErrLengthMismatch, err)
+}
+
+func TestMul(t *testing.T) {
+	require := require.New(t)
+
+	v, err := New(1, 2, 3).Mul(New(2, 2, 0.5))
+	require.NoError(err)
+	require.True(Equal(New(2, 4, 1.5), v))
+
+	_, err = New(1).Mul(New(nil))
+	require.Error(err)
+}
+
+func TestDiv(t *testing.T) {
+	require := require.New(t)
+
+	v, err := New(1, 6, 3.0).Div(New(2, 3, 2))
+	require.NoError(err)
+	require.True(Equal(New(0.5, 2.0, 1.5), v))
+
+	_, err = New(1, 2).Div(New(1))
+	require.Equal(ErrLengthMismatch, err)
+}
+
+func TestDot(t *testing.T) {
+	require := require.New(t)
+
+	dot, err := New(1, 2, 3).Dot(New(4, 5, 6.5))
+	require.NoError(err)
+	require.Equal(4+10+19.5, dot)
+
+	dot, err = New().Dot(New())
+	require.NoError(err)
+	require.Equal(0.0, dot)
+
+	_, err = New(1, 2).Dot(New(1))
+	require.Equal(ErrLengthMismatch, err)
+
+	_, err = New(1, 2).Dot(New(1, "a"))
+	require.Error(err)
+}
+
+func TestNorm(t *testing.T) {
+	require := require.New(t)
+
+	norm, err := New(3, 4.0).Norm()
+	require.NoError(err)
+	require.Equal(5.0, norm)
+
+	norm, err = New().Norm()
+	require.NoError(err)
+	require.Equal(0.0, norm)
+
+	_, err = New(1, true).Norm()
+	require.EqualError(err, "vector: element 1 is not numeric: bool")
+}
+
+func TestEqualApprox(t *testing.T) {
+	require := require.New(t)
+
+	v := New(1.0, 2.0, "a", 3)
+	require.True(EqualApprox(v, New(1.0005, 1.9995, "a", 3), 0.001))
+	require.False(EqualApprox(v, New(1.01, 2.0, "a", 3), 0.001))
+	require.False(EqualApprox(v, New(1.0, 2.0, "b", 3), 0.001))
+	require.False(EqualApprox(v, New(1.0, 2.0, "a", 3.0), 0.001))
+	require.False(EqualApprox(v, New(1.0, 2.0), 0.001))
+	require.True(EqualApprox(New(float32(1.5)), New(1.5), 0))
+}
+
+func TestScale(t *testing.T) {
+	require := require.New(t)
+
+	v, err := New(1, 2, 3).Scale(2)
+	require.NoError(err)
+	require.True(Equal(New(2.0, 4.0, 6.0), v))
+
+	v, err = New(1.5, int64(-2)).Scale(-0.5)
+	require.NoError(err)
+	require.True(Equal(New(-0.75, 1.0), v))
+
+	_, err = New(1, "a").Scale(2)
+	require.EqualError(err, "vector: element 1 is not numeric: string")
+}
+
+func TestOffset(t *testing.T) {
+	require := require.New(t)
+
+	v, err := New(1, 2, 3).Offset(0.5)
+	require.NoError(err)
+	require.True(Equal(New(1.5, 2.5, 3.5), v))
+
+	v, err = New().Offset(1)
+	require.NoError(err)
+	require.Equal(0, v.Count())
+
+	_, err = New(nil).Offset(1)
+	require.Error(err)
+}
+
+func TestClamp(t *testing.T) {
+	require := require.New(t)
+
+	v, err := New(-1, 0.5, 2).Clamp(0, 1)
+	require.NoError(err)
+	require.True(Equal(New(0.0, 0.5, 1.0), v))
+
+	v, err = New(3, 7).Clamp(5, 5)
+	require.NoError(err)
+	require.True(Equal(New(5.0, 5.0), v))
+
+	_, err = New(1, "a").Clamp(0, 1)
+	require.Error(err)
+
+	require.Panics(func() {
+		_, _ = New(1).Clamp(1, 0)
+	})
+}
+
+func TestNormalize(t *testing.T) {
+	require := require.New(t)
+
+	v, err := New(3, 4).Normalize()
+	require.NoError(err)
+	require.True(EqualApprox(New(0.6, 0.8), v, 1e-9))
+
+	v, err = New(1, -2.5, 7, 0.25).Normalize()
+	require.NoError(err)
+	norm, err := v.Norm()
+	require.NoError(err)
+	require.InDelta(1.0, norm, 1e-9)
+
+	_, err = New(0, 0.0).Normalize()
+	require.Error(err)
+
+	_, err = New().Normalize()
+	require.Error(err)
+
+	_, err = New(1, "a").Normalize()
+	require.Error(err)
+}
+
+func TestMean(t *testing.T) {
+	require := require.New(t)
+
+	mean, err := New(2.0, 4.0, 4.0, 4.0, 5.0, 5.0, 7.0, 9.0).Mean()
+	require.NoError(err)
+	require.InDelta(5.0, mean, 1e-9)
+
+	mean, err = New(1, 2).Mean()
+	require.NoError(err)
+	require.Equal(1.5, mean)
+
+	_, err = New().Mean()
+	require.Equal(ErrEmpty, err)
+
+	_, err = New(1, "a").Mean()
+	require.EqualError(err, "vector: element 1 is not numeric: string")
+}
+
+func TestVariance(t *testing.T) {
+	require := require.New(t)
+
+	variance, err := New(2.0, 4.0, 4.0, 4.0, 5.0, 5.0, 7.0, 9.0).Variance()
+	require.NoError(err)
+	require.InDelta(4.0, variance, 1e-9)
+
+	variance, err = New(1e9+4, 1e9+7, 1e9+13, 1e9+16).Variance()
+	require.NoError(err)
+	require.InDelta(22.5, variance, 1e-6)
+
+	variance, err = New(3).Variance()
+	require.NoError(err)
+	require.Equal(0.0, variance)
+
+	_, err = New().Variance()
+	require.Equal(ErrEmpty, err)
+}
+
+func TestStdDev(t *testing.T) {
+	require := require.New(t)
+
+	stddev, err := New(2.0, 4.0, 4.0, 4.0, 5.0, 5.0, 7.0, 9.0).StdDev()
+	require.NoError(err)
+	require.InDelta(2.0, stddev, 1e-9)
+
+	_, err = New(1, nil).StdDev()
+	require.Error(err)
+}
+
+func TestCountInRange(t *testing.T) {
+	require := require.New(t)
+
+	n, err := New(1, 2, 3, 4, 5).CountInRange(2, 4)
+	require.NoError(err)
+	require.Equal(3, n)
+
+	n, err = New(0.5, 1.5, int8(2), uint(7)).CountInRange(1, 2)
+	require.NoError(err)
+	require.Equal(2, n)
+
+	n, err = New(1, 2).CountInRange(3, 2)
+	require.NoError(err)
+	require.Equal(0, n)
+
+	_, err = New(1, "a").CountInRange(0, 10)
+	require.EqualError(err, "vector: element 1 is not numeric: string")
+}
+
+func TestPercentile(t *testing.T) {
+	require := require.New(t)
+
+	v := New(15.0, 20.0, 35.0, 40.0, 50.0)
+	testCases := []struct {
+		p        float64
+		expected float64
+	}{
+		{0, 15},
+		{25, 20},
+		{40, 29},
+		{50, 35},
+		{90, 46},
+		{100, 50},
+	}
+
+	for _, tt := range testCases {
+		x, err := v.Percentile(tt.p)
+		require.NoError(err)
+		require.InDelta(tt.expected, x, 1e-9, "percentile %v", tt.p)
+	}
+
+	x, err := New(3, 1, 2).Percentile(50)
+	require.NoError(err)
+	require.Equal(2.0, x)
+
+	_, err = New().Percentile(50)
+	require.Equal(ErrEmpty, err)
+
+	_, err = v.Percentile(101)
+	require.Error(err)
+
+	_, err = New(1, "a").Percentile(50)
+	require.Error(err)
+}
+
+func TestMedian(t *testing.T) {
+	require := require.New(t)
+
+	x, err := New(4, 1, 3, 2).Median()
+	require.NoError(err)
+	require.Equal(2.5, x)
+
+	x, err = New(7.5).Median()
+	require.NoError(err)
+	require.Equal(7.5, x)
+}
+
+func TestConvolve(t *testing.T) {
+	require := require.New(t)
+
+	avg := []float64{1.0 / 3, 1.0 / 3, 1.0 / 3}
+	v, err := New(3, 6, 9, 3.0, 12).Convolve(avg)
+	require.NoError(err)
+	require.True(EqualApprox(New(6.0, 6.0, 8.0), v, 1e-9))
+
+	// The kernel is flipped, as in a convolution and not a correlation.
+	v, err = New(1, 2, 3).Convolve([]float64{1, 0})
+	require.NoError(err)
+	require.True(Equal(New(2.0, 3.0), v))
+
+	v, err = New(1, 2).Convolve(avg)
+	require.NoError(err)
+	require.Equal(0, v.Count())
+
+	_, err = New(1, "a", 3).Convolve(avg)
+	require.Error(err)
+
+	require.Panics(func() {
+		_, _ = New(1, 2, 3).Convolve(nil)
+	})
+}
+
+func TestDeltaEncode(t *testing.T) {
+	require := require.New(t)
+
+	v := New(1000, 1003, 1004, 1010, 1010)
+	encoded, err := v.DeltaEncode()
+	require.NoError(err)
+	require.True(Equal(New(1000, 3, 1, 6, 0), encoded))
+
+	decoded, err := encoded.DeltaDecode()
+	require.NoError(err)
+	require.True(Equal(v, decoded))
+
+	big := makeVector(2000).Drop(5)
+	encoded, err = big.DeltaEncode()
+	require.NoError(err)
+	decoded, err = encoded.DeltaDecode()
+	require.NoError(err)
+	require.True(Equal(big, decoded))
+
+	encoded, err = New(1.5, 2.0, 3).DeltaEncode()
+	require.NoError(err)
+	require.True(Equal(New(1.5, 0.5, 1.0), encoded))
+
+	encoded, err = New().DeltaEncode()
+	require.NoError(err)
+	require.Equal(0, encoded.Count())
+
+	_, err = New(1, "a").DeltaEncode()
+	require.EqualError(err, "vector: element 1 is not numeric: string")
+
+	_, err = New(1, nil).DeltaDecode()
+	require.EqualError(err, "vector: element 1 is not numeric: <nil>")
+}
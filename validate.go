@@ -0,0 +1,104 @@
+package vector
+
+import "fmt"
+
+// Validate checks that the internal structure of the vector is consistent with
+// the number of elements it holds, and returns an error describing the first
+// inconsistency found, if any.
+func (v *Vector) Validate() error {
+	if v.start < 0 || (v.start > 0 && uint64(v.start) >= v.count) {
+		return fmt.Errorf("vector: start %d is out of bounds of a vector "+
+			"with %d elements", v.start, v.count)
+	}
+
+	if v.shift < uint(vectorBits) || v.shift%uint(vectorBits) != 0 {
+		return fmt.Errorf("vector: invalid shift %d", v.shift)
+	}
+
+	tailOffset := v.tailOffset()
+	if uint64(len(v.tail.values)) != v.count-tailOffset {
+		return fmt.Errorf("vector: tail has %d elements, expected %d",
+			len(v.tail.values), v.count-tailOffset)
+	}
+
+	if tailOffset > uint64(1)<<(v.shift+uint(vectorBits)) {
+		return fmt.Errorf("vector: %d elements do not fit in a trie with shift %d",
+			tailOffset, v.shift)
+	}
+
+	return validateNode(v.root, v.shift, 0, tailOffset)
+}
+
+// validateNode checks that a node at the given level holding the elements
+// starting at base contains all elements up to limit and none past it.
+func validateNode(n *node, shift uint, base, limit uint64) error {
+	if len(n.values) != int(vectorWidth) {
+		return fmt.Errorf("vector: node at level %d for element %d has %d values",
+			shift/uint(vectorBits), base, len(n.values))
+	}
+
+	if shift == 0 {
+		return nil
+	}
+
+	for i, value := range n.values {
+		key := base + uint64(i)<<shift
+		child, ok := value.(*node)
+		if key >= limit {
+			if value != nil {
+				return fmt.Errorf("vector: unexpected node at level %d for element %d",
+					shift/uint(vectorBits)-1, key)
+			}
+			continue
+		}
+
+		if !ok {
+			return fmt.Errorf("vector: missing node at level %d for element %d",
+				shift/uint(vectorBits)-1, key)
+		}
+
+		if err := validateNode(child, shift-uint(vectorBits), key, limit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rebalance returns the vector if its structure is valid according to
+// Validate. Otherwise, it returns a new vector with a canonical structure built
+// from the elements stored in the nodes of the vector, in order. Nodes that
+// contain other nodes are considered inner nodes and the rest leaves.
+func (v *Vector) Rebalance() *Vector {
+	if v.Validate() == nil {
+		return v
+	}
+
+	var elems []interface{}
+	if v.root != emptyNode {
+		elems = collectElements(elems, v.root)
+	}
+	elems = append(elems, v.tail.values...)
+
+	if v.start > 0 && v.start < len(elems) {
+		elems = elems[v.start:]
+	} else if v.start > 0 {
+		elems = nil
+	}
+
+	return fromSlice(elems)
+}
+
+func collectElements(elems []interface{}, n *node) []interface{} {
+	var inner bool
+	for _, value := range n.values {
+		if child, ok := value.(*node); ok {
+			inner = true
+			elems = collectElements(elems, child)
+		}
+	}
+
+	if inner {
+		return elems
+	}
+	return append(elems, n.values...)
+}
@@ -0,0 +1,52 @@
+package vector
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	require := require.New(t)
+
+	for _, n := range []int{0, 1, 32, 33, 1056, 1057, 5000} {
+		require.NoError(makeVector(n).Validate(), "size %d", n)
+	}
+	require.NoError(makeVector(100).Drop(40).Validate())
+	require.NoError(makeVector(2000).Set(1000, -1).Validate())
+
+	v := makeVector(100)
+	malformed := []*Vector{
+		{40, 5, emptyNode, &node{makeVector(40).Slice()}, 0},
+		{v.count, 10, v.root, v.tail, 0},
+		{v.count, 5, v.root, v.tail, 100},
+		{v.count + 32, 5, v.root, v.tail, 0},
+		{v.count, 5, v.root.cloneWithLen(2), v.tail, 0},
+	}
+	for _, m := range malformed {
+		require.Error(m.Validate())
+	}
+}
+
+func TestRebalance(t *testing.T) {
+	require := require.New(t)
+
+	v := makeVector(100)
+	require.True(v == v.Rebalance())
+
+	malformed := &Vector{40, 5, emptyNode, &node{makeVector(40).Slice()}, 0}
+	result := malformed.Rebalance()
+	require.NoError(result.Validate())
+	require.True(Equal(makeVector(40), result))
+
+	malformed = &Vector{v.count, 10, v.root, v.tail, 3}
+	result = malformed.Rebalance()
+	require.NoError(result.Validate())
+	require.True(Equal(v.Drop(3), result))
+
+	withNils := New(make([]interface{}, 70)...)
+	malformed = &Vector{withNils.count, 10, withNils.root, withNils.tail, 0}
+	result = malformed.Rebalance()
+	require.NoError(result.Validate())
+	require.True(Equal(withNils, result))
+}
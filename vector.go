@@ -1,14 +1,22 @@
 package vector
 
 import (
+	"container/heap"
 	"errors"
 	"fmt"
+	"io"
+	"math/rand"
 	"reflect"
+	"sort"
 	"strings"
 )
 
 // Vector implements a persistent bit-partitioned vector trie, an array-like
 // persistent data structure.
+//
+// A nil *Vector is treated as an empty vector by Get, Count, IsEmpty, Slice,
+// Range, String and Append, so the zero value of a *Vector variable can be
+// used right away.
 type Vector struct {
 	count uint64
 	shift uint
@@ -19,42 +27,183 @@ type Vector struct {
 
 // New returns a new vector containing the given elements.
 func New(elems ...interface{}) *Vector {
-	v := emptyVector
-	for _, e := range elems {
-		v = v.Append(e)
+	return fromSlice(elems)
+}
+
+// FromArray returns a new vector containing the elements of the given array,
+// which must be a Go array, such as [3]int, not a slice.
+func FromArray(arr interface{}) (*Vector, error) {
+	value := reflect.ValueOf(arr)
+	if value.Kind() != reflect.Array {
+		return nil, fmt.Errorf("vector: expected an array, got %T", arr)
 	}
-	return v
+
+	b := newBuilder(value.Len())
+	for i := 0; i < value.Len(); i++ {
+		b.append(value.Index(i).Interface())
+	}
+	return b.vector(), nil
+}
+
+// FromSparse returns a new vector with n elements, in which the element at
+// every index in m is the one in m and the rest are zero. It is the inverse of
+// Sparse. FromSparse panics if any index in m is out of bounds.
+func FromSparse(n int, m map[int]interface{}, zero interface{}) *Vector {
+	if n < 0 {
+		panic("cannot create a vector with less than 0 items")
+	}
+
+	for i := range m {
+		if i < 0 || i >= n {
+			panic(fmt.Errorf("vector: index out of bounds, tried to set "+
+				"element %d of a vector with %d elements", i, n))
+		}
+	}
+
+	b := newBuilder(n)
+	for i := 0; i < n; i++ {
+		if elem, ok := m[i]; ok {
+			b.append(elem)
+		} else {
+			b.append(zero)
+		}
+	}
+	return b.vector()
+}
+
+// FromSortedMap returns a new vector with the elements of m ordered by their
+// keys, each one at the position given by its key. The vector has as many
+// elements as the largest key plus one, and the positions that are not in m
+// are nil. If any key is negative, it will panic.
+func FromSortedMap(m map[int]interface{}) *Vector {
+	n := 0
+	for i := range m {
+		if i < 0 {
+			panic(fmt.Errorf("vector: cannot create a vector with negative index %d", i))
+		}
+
+		if i >= n {
+			n = i + 1
+		}
+	}
+	return FromSparse(n, m, nil)
+}
+
+// Iterate returns a new vector with n elements, the first of which is seed and
+// each of the others the result of calling f with the previous one.
+func Iterate(seed interface{}, n int, f func(interface{}) interface{}) *Vector {
+	if n < 0 {
+		panic("cannot create a vector with less than 0 items")
+	}
+
+	b := newBuilder(n)
+	for elem := seed; n > 0; n-- {
+		b.append(elem)
+		if n > 1 {
+			elem = f(elem)
+		}
+	}
+	return b.vector()
+}
+
+// maxUnfold is the maximum number of elements Unfold can produce before it
+// assumes the function never stops.
+const maxUnfold = 1 << 26
+
+// Unfold returns a new vector with the elements produced by calling f, first
+// with seed and then with the state returned by the previous call, until it
+// returns false, which is the reverse of folding a vector into a single value.
+// To protect against functions that never stop, Unfold panics after producing
+// 1<<26 elements.
+func Unfold(seed interface{}, f func(state interface{}) (elem, next interface{}, ok bool)) *Vector {
+	b := newBuilder(0)
+	for state := seed; ; {
+		elem, next, ok := f(state)
+		if !ok {
+			break
+		}
+
+		if b.count == maxUnfold {
+			panic(fmt.Errorf("vector: unfold produced more than %d elements", maxUnfold))
+		}
+		b.append(elem)
+		state = next
+	}
+	return b.vector()
 }
 
 // Append returns a new vector appending the element at the end of the vector.
 func (v *Vector) Append(elem interface{}) *Vector {
+	if v == nil {
+		v = emptyVector
+	}
+
 	if v.count-v.tailOffset() < uint64(vectorWidth) {
 		lenTail := len(v.tail.values)
 		tail := v.tail.cloneWithLen(lenTail + 1)
 		tail.values[lenTail] = elem
-		return &Vector{v.count + 1, v.shift, v.root, tail, 0}
+		return &Vector{v.count + 1, v.shift, v.root, tail, v.start}
 	}
 
-	var root *node
-	tail := v.tail
-	shift := v.shift
-	if (v.count >> vectorBits) > (1 << v.shift) {
-		root = &node{make([]interface{}, vectorWidth)}
-		root.values[0] = v.root
-		root.values[1] = newPath(tail)
-		shift += uint(vectorBits)
-	} else {
-		root = v.pushTail(shift, v.root, tail)
+	return v.pushLeaf(&node{[]interface{}{elem}})
+}
+
+// AppendVector returns a new vector appending all the elements of other at
+// the end of the vector. When the tail of the vector is full and the elements
+// of other start at the beginning of a leaf, which is common when other was
+// built from the vector, the leaves of other are attached to the new vector as
+// they are instead of copying their elements one by one.
+func (v *Vector) AppendVector(other *Vector) *Vector {
+	if other.Count() == 0 {
+		return v
+	}
+
+	if v.Count() == 0 {
+		return other
+	}
+
+	if v.count&uint64(vectorMask) != 0 || other.start&int(vectorMask) != 0 {
+		result := v
+		for i := 0; i < other.Count(); i++ {
+			result = result.Append(other.Get(i))
+		}
+		return result
+	}
+
+	result := v
+	for key := uint64(other.start); key < other.count; key += uint64(vectorWidth) {
+		result = result.pushLeaf(other.leafFor(key))
+	}
+	return result
+}
+
+// ConcatAll returns a new vector with the elements of all the given vectors,
+// in order. The resulting vector is built once, which is faster than
+// appending the vectors one by one.
+func ConcatAll(vs ...*Vector) *Vector {
+	var size int
+	for _, v := range vs {
+		size += v.Count()
 	}
 
-	tail = &node{[]interface{}{elem}}
-	return &Vector{v.count + 1, shift, root, tail, 0}
+	b := newBuilder(size)
+	for _, v := range vs {
+		_ = v.each(0, v.Count(), func(_ int, elem interface{}) error {
+			b.append(elem)
+			return nil
+		})
+	}
+	return b.vector()
 }
 
 // Get returns the element at the given position. If the position is negative, returns
 // elements in reverse order. If the element cannot be found in the vector, it
 // will return nil.
 func (v *Vector) Get(i int) interface{} {
+	if v == nil {
+		return nil
+	}
+
 	var key = uint64(i + v.start)
 	if i < 0 {
 		key = v.count + uint64(i)
@@ -64,17 +213,7 @@ func (v *Vector) Get(i int) interface{} {
 		return nil
 	}
 
-	tailOffset := v.tailOffset()
-	if tailOffset == 0 || tailOffset-1 < key {
-		return v.tail.values[key-tailOffset]
-	}
-
-	n := v.root
-	for lvl := v.shift; lvl > 0; lvl -= uint(vectorBits) {
-		n = n.values[(key>>lvl)&uint64(vectorMask)].(*node)
-	}
-
-	return n.values[key&uint64(vectorMask)]
+	return v.leafFor(key).values[key&uint64(vectorMask)]
 }
 
 // Set will change the value of the element at the given index. If the element
@@ -110,24 +249,364 @@ func (v *Vector) Set(i int, elem interface{}) *Vector {
 	return &Vector{v.count, v.shift, root, v.tail, v.start}
 }
 
+// SetIfChanged works like Set, but returns the vector itself, without copying
+// anything, if the element at the given index is already equal to elem,
+// according to reflect.DeepEqual.
+func (v *Vector) SetIfChanged(i int, elem interface{}) *Vector {
+	n := v.Count()
+	if i < n && i >= -n && reflect.DeepEqual(v.Get(i), elem) {
+		return v
+	}
+	return v.Set(i, elem)
+}
+
+// Overlay returns a new vector with the elements of the vector replaced with
+// the elements of other at the same positions. Elements of other past the end
+// of the vector are ignored, so the result always has as many elements as the
+// vector, and elements of the vector past the end of other are kept.
+func (v *Vector) Overlay(other *Vector) *Vector {
+	n, m := v.Count(), other.Count()
+	if m >= n {
+		return other.sub(0, n)
+	}
+
+	if m == 0 {
+		return v
+	}
+
+	// update visits the elements in order, so the first m replacements are
+	// the elements of other, in order.
+	c := &cursor{v: other}
+	return v.update(func(interface{}) (interface{}, bool) {
+		return c.next()
+	})
+}
+
+// SwapRanges returns a new vector with the aLen elements starting at aStart
+// and the bLen elements starting at bStart swapped. The ranges may have
+// different lengths, in which case the elements between them are shifted, but
+// they cannot overlap and must be within the bounds of the vector.
+func (v *Vector) SwapRanges(aStart, aLen, bStart, bLen int) (*Vector, error) {
+	n := v.Count()
+	if aStart < 0 || aLen < 0 || aStart+aLen > n || bStart < 0 || bLen < 0 || bStart+bLen > n {
+		return nil, fmt.Errorf("vector: cannot swap ranges [%d, %d) and [%d, %d) "+
+			"of a vector with %d elements", aStart, aStart+aLen, bStart, bStart+bLen, n)
+	}
+
+	if bStart < aStart {
+		aStart, aLen, bStart, bLen = bStart, bLen, aStart, aLen
+	}
+
+	aEnd, bEnd := aStart+aLen, bStart+bLen
+	if aEnd > bStart {
+		return nil, fmt.Errorf("vector: cannot swap overlapping ranges "+
+			"[%d, %d) and [%d, %d)", aStart, aEnd, bStart, bEnd)
+	}
+
+	b := newBuilder(n)
+	for _, r := range [][2]int{{0, aStart}, {bStart, bEnd}, {aEnd, bStart}, {aStart, aEnd}, {bEnd, n}} {
+		_ = v.each(r[0], r[1], func(_ int, elem interface{}) error {
+			b.append(elem)
+			return nil
+		})
+	}
+	return b.vector(), nil
+}
+
+// Replace returns a new vector with every element equal to old, according to
+// reflect.DeepEqual, replaced with new. Only the nodes containing replaced
+// elements are copied and, if no element is replaced, the vector is returned
+// unchanged.
+func (v *Vector) Replace(old, new interface{}) *Vector {
+	return v.ReplaceFunc(func(elem interface{}) bool {
+		return reflect.DeepEqual(elem, old)
+	}, new)
+}
+
+// ReplaceFirst returns a new vector with the first element equal to old,
+// according to reflect.DeepEqual, replaced with new. If no element is equal
+// to old, the vector is returned unchanged.
+func (v *Vector) ReplaceFirst(old, new interface{}) *Vector {
+	idx := -1
+	_ = v.each(0, v.Count(), func(i int, elem interface{}) error {
+		if reflect.DeepEqual(elem, old) {
+			idx = i
+			return ErrStop
+		}
+		return nil
+	})
+
+	if idx < 0 {
+		return v
+	}
+	return v.Set(idx, new)
+}
+
+// ReplaceFunc returns a new vector with every element that satisfies the
+// given function replaced with new. Only the nodes containing replaced
+// elements are copied and, if no element is replaced, the vector is returned
+// unchanged.
+func (v *Vector) ReplaceFunc(match func(interface{}) bool, new interface{}) *Vector {
+	return v.update(func(elem interface{}) (interface{}, bool) {
+		if match(elem) {
+			return new, true
+		}
+		return nil, false
+	})
+}
+
+// MapWhere returns a new vector with every element that satisfies match
+// replaced with the result of calling transform with it. Only the nodes
+// containing transformed elements are copied and, if no element matches, the
+// vector is returned unchanged.
+func (v *Vector) MapWhere(
+	match func(interface{}) bool,
+	transform func(interface{}) interface{},
+) *Vector {
+	return v.update(func(elem interface{}) (interface{}, bool) {
+		if match(elem) {
+			return transform(elem), true
+		}
+		return nil, false
+	})
+}
+
+// Coalesce returns a new vector with every nil element replaced with def. Only
+// the nodes containing nil elements are copied and, if there are none, the
+// vector is returned unchanged.
+func (v *Vector) Coalesce(def interface{}) *Vector {
+	return v.update(func(elem interface{}) (interface{}, bool) {
+		return def, elem == nil
+	})
+}
+
+// update returns a new vector replacing every element for which f returns
+// true with the value returned by f. Nodes without replaced elements are
+// shared with the vector and, if there are no replacements at all, the vector
+// itself is returned.
+func (v *Vector) update(f func(elem interface{}) (interface{}, bool)) *Vector {
+	start := uint64(v.start)
+	root, rootChanged := updateNode(v.root, v.shift, 0, start, f)
+	tail, tailChanged := updateNode(v.tail, 0, v.tailOffset(), start, f)
+	if !rootChanged && !tailChanged {
+		return v
+	}
+	return &Vector{v.count, v.shift, root, tail, v.start}
+}
+
+// updateNode returns a copy of a node at the given level, whose first element
+// has the key base, with the replacements made by f on the elements starting
+// at start, or the same node if nothing was replaced.
+func updateNode(
+	n *node,
+	shift uint,
+	base, start uint64,
+	f func(elem interface{}) (interface{}, bool),
+) (*node, bool) {
+	var result *node
+	for i, value := range n.values {
+		key := base + uint64(i)<<shift
+		if key+1<<shift <= start {
+			continue
+		}
+
+		var changed bool
+		if shift == 0 {
+			value, changed = f(value)
+		} else if child, ok := value.(*node); ok {
+			value, changed = updateNode(child, shift-uint(vectorBits), key, start, f)
+		}
+
+		if changed {
+			if result == nil {
+				result = n.clone()
+			}
+			result.values[i] = value
+		}
+	}
+
+	if result == nil {
+		return n, false
+	}
+	return result, true
+}
+
 // ErrStop may be returned to stop iterating a vector.
 var ErrStop = errors.New("stop")
 
+// ErrTooManyErrors is returned by RangeTolerant when the iteration is aborted
+// because the function returned too many errors.
+var ErrTooManyErrors = errors.New("vector: too many errors")
+
 // Range iterates over the vector to access all its elements. In order to stop
 // the iteration, ErrStop may be returned. Any other error will also terminate
 // the iteration and will also return that error.
 func (v *Vector) Range(f func(a interface{}) error) error {
-	for i := 0; i < int(v.count); i++ {
-		if err := f(v.Get(i)); err != nil {
-			if err == ErrStop {
-				return nil
-			}
+	return v.each(0, v.Count(), func(_ int, elem interface{}) error {
+		return f(elem)
+	})
+}
+
+// RangeTolerant iterates over the vector like Range, but it keeps iterating
+// when the function returns an error, collecting the errors instead, until
+// maxErrors errors have been collected. In that case the iteration is aborted
+// and ErrTooManyErrors is returned along with the collected errors. As with
+// Range, ErrStop may be returned to stop the iteration, and it isn't collected.
+// If maxErrors is less than 1, it will panic.
+func (v *Vector) RangeTolerant(maxErrors int, f func(a interface{}) error) ([]error, error) {
+	if maxErrors < 1 {
+		panic("cannot tolerate less than 1 error")
+	}
+
+	var errs []error
+	err := v.each(0, v.Count(), func(_ int, elem interface{}) error {
+		err := f(elem)
+		if err == nil || err == ErrStop {
 			return err
 		}
+
+		errs = append(errs, err)
+		if len(errs) == maxErrors {
+			return ErrTooManyErrors
+		}
+		return nil
+	})
+	return errs, err
+}
+
+// Tap calls the given function with every element of the vector and returns
+// the vector unchanged, which is useful to inspect the elements in a chain of
+// operations.
+func (v *Vector) Tap(f func(interface{})) *Vector {
+	_ = v.each(0, v.Count(), func(_ int, elem interface{}) error {
+		f(elem)
+		return nil
+	})
+	return v
+}
+
+// Pipe returns the result of applying the given functions to the vector in
+// order, each one to the result of the previous one. With no functions, the
+// vector is returned unchanged.
+func (v *Vector) Pipe(fns ...func(*Vector) *Vector) *Vector {
+	result := v
+	for _, f := range fns {
+		result = f(result)
+	}
+	return result
+}
+
+// each calls f with the logical index and value of every element in the range
+// [lo, hi), walking the leaves of the trie directly instead of looking up
+// every element from the root. Iteration stops at the first error, which is
+// returned unless it's ErrStop. A nil vector has no elements.
+func (v *Vector) each(lo, hi int, f func(i int, elem interface{}) error) error {
+	if v == nil {
+		return nil
+	}
+
+	start := uint64(v.start)
+	key, end := uint64(lo)+start, uint64(hi)+start
+	for key < end {
+		leaf := v.leafFor(key)
+		for j := key & uint64(vectorMask); j < uint64(len(leaf.values)) && key < end; j++ {
+			if err := f(int(key-start), leaf.values[j]); err != nil {
+				if err == ErrStop {
+					return nil
+				}
+				return err
+			}
+			key++
+		}
 	}
 	return nil
 }
 
+// elements returns the elements in the range [lo, hi) in a slice.
+func (v *Vector) elements(lo, hi int) []interface{} {
+	elems := make([]interface{}, 0, hi-lo)
+	_ = v.each(lo, hi, func(_ int, elem interface{}) error {
+		elems = append(elems, elem)
+		return nil
+	})
+	return elems
+}
+
+// cursor iterates over the elements of a vector, looking up each leaf only
+// once instead of once per element.
+type cursor struct {
+	v    *Vector
+	i    int
+	leaf *node
+}
+
+// next returns the next element of the vector and whether there was one.
+func (c *cursor) next() (interface{}, bool) {
+	if c.i >= c.v.Count() {
+		return nil, false
+	}
+
+	key := uint64(c.i + c.v.start)
+	if c.leaf == nil || key&uint64(vectorMask) == 0 {
+		c.leaf = c.v.leafFor(key)
+	}
+	c.i++
+	return c.leaf.values[key&uint64(vectorMask)], true
+}
+
+// Pairwise iterates over every pair of adjacent elements in the vector. As with
+// Range, ErrStop may be returned to stop the iteration and any other error will
+// terminate the iteration and will be returned.
+func (v *Vector) Pairwise(f func(a, b interface{}) error) error {
+	var prev interface{}
+	return v.each(0, v.Count(), func(i int, elem interface{}) error {
+		if i > 0 {
+			if err := f(prev, elem); err != nil {
+				return err
+			}
+		}
+		prev = elem
+		return nil
+	})
+}
+
+// Triples iterates over every three consecutive elements in the vector, so a
+// vector with n elements calls f n-2 times. As with Range, ErrStop may be
+// returned to stop the iteration and any other error will terminate the
+// iteration and will be returned.
+func (v *Vector) Triples(f func(a, b, c interface{}) error) error {
+	var a, b interface{}
+	return v.each(0, v.Count(), func(i int, c interface{}) error {
+		if i >= 2 {
+			if err := f(a, b, c); err != nil {
+				return err
+			}
+		}
+		a, b = b, c
+		return nil
+	})
+}
+
+// Deltas returns a new vector with the differences between every two adjacent
+// elements of the vector, computed as sub(next, previous). The resulting
+// vector has one element less than the vector, or none if it's empty.
+func (v *Vector) Deltas(sub func(a, b interface{}) interface{}) *Vector {
+	b := newBuilder(v.Count() - 1)
+	_ = v.Pairwise(func(prev, next interface{}) error {
+		b.append(sub(next, prev))
+		return nil
+	})
+	return b.vector()
+}
+
+// Integrate returns a new vector starting with init and followed by the
+// running totals of adding every element of the vector to the previous total
+// with add. It's the inverse of Deltas: integrating the deltas of a vector
+// with its first element as init results in the vector.
+func (v *Vector) Integrate(init interface{}, add func(a, b interface{}) interface{}) *Vector {
+	return ConcatAll(New(init), v).scan(add)
+}
+
 // First returns the first element of the vector.
 func (v *Vector) First() interface{} {
 	return v.Get(0)
@@ -145,9 +624,48 @@ func (v *Vector) Tail() *Vector {
 
 // Count returns the number of elements in the vector.
 func (v *Vector) Count() int {
+	if v == nil {
+		return 0
+	}
 	return int(v.count) - int(v.start)
 }
 
+// IsEmpty returns whether the vector has no elements.
+func (v *Vector) IsEmpty() bool {
+	return v.Count() == 0
+}
+
+// leafFor returns the leaf node containing the element with the given key,
+// which may be the tail.
+func (v *Vector) leafFor(key uint64) *node {
+	if key >= v.tailOffset() {
+		return v.tail
+	}
+
+	n := v.root
+	for lvl := v.shift; lvl > 0; lvl -= uint(vectorBits) {
+		n = n.values[(key>>lvl)&uint64(vectorMask)].(*node)
+	}
+	return n
+}
+
+// pushLeaf returns a new vector with the tail, which must be full, pushed
+// into the trie and the given leaf as its new tail.
+func (v *Vector) pushLeaf(leaf *node) *Vector {
+	var root *node
+	shift := v.shift
+	if (v.count >> vectorBits) > (1 << v.shift) {
+		root = &node{make([]interface{}, vectorWidth)}
+		root.values[0] = v.root
+		root.values[1] = newPath(v.shift, v.tail)
+		shift += uint(vectorBits)
+	} else {
+		root = v.pushTail(shift, v.root, v.tail)
+	}
+
+	return &Vector{v.count + uint64(len(leaf.values)), shift, root, leaf, v.start}
+}
+
 // pushTail pushes the tail to the rightmost node available and returns a new root.
 func (v *Vector) pushTail(shift uint, root, tail *node) *node {
 	newRoot := root.clone()
@@ -158,7 +676,7 @@ func (v *Vector) pushTail(shift uint, root, tail *node) *node {
 		if n, ok := root.values[idx].(*node); ok {
 			newNode = v.pushTail(shift, n, tail)
 		} else {
-			newNode = newPath(tail)
+			newNode = newPath(shift, tail)
 		}
 	}
 
@@ -176,7 +694,7 @@ func (v *Vector) tailOffset() uint64 {
 
 // Slice returns the elements of the vector in a slice.
 func (v *Vector) Slice() []interface{} {
-	size := int(v.count) - v.start
+	size := v.Count()
 	var result = make([]interface{}, size)
 	for i := 0; i < size; i++ {
 		result[i] = v.Get(i)
@@ -184,11 +702,72 @@ func (v *Vector) Slice() []interface{} {
 	return result
 }
 
+// TailSlice returns a copy of the elements in the tail of the vector, which are
+// the most recently appended ones. The tail never holds more than 32 elements,
+// so this is much cheaper than Slice when only the last few elements are
+// needed, but the number of elements returned depends on how the vector is
+// laid out.
+func (v *Vector) TailSlice() []interface{} {
+	if v.Count() == 0 {
+		return []interface{}{}
+	}
+
+	tailOffset := v.tailOffset()
+	values := v.tail.values[:v.count-tailOffset]
+	if start := uint64(v.start); start > tailOffset {
+		values = values[start-tailOffset:]
+	}
+
+	result := make([]interface{}, len(values))
+	copy(result, values)
+	return result
+}
+
+// TailLen returns the number of elements in the tail of the vector, which is
+// always the number of elements returned by TailSlice. Appending to a vector
+// whose tail is full moves the tail into the trie and starts a new one. The
+// tail of a dropped vector may also hold some of the dropped elements, which
+// are not counted, so it may be full before TailLen reaches TailCap.
+func (v *Vector) TailLen() int {
+	if v == nil {
+		return 0
+	}
+
+	tailOffset := v.tailOffset()
+	if start := uint64(v.start); start > tailOffset {
+		return int(v.count - start)
+	}
+	return int(v.count - tailOffset)
+}
+
+// TailCap returns the maximum number of elements the tail of a vector can hold.
+func (v *Vector) TailCap() int {
+	return int(vectorWidth)
+}
+
+// Bytes returns the elements of the vector in a byte slice. All elements in
+// the vector must be of type byte, otherwise an error will be returned.
+func (v *Vector) Bytes() ([]byte, error) {
+	result := make([]byte, 0, v.Count())
+	err := v.each(0, v.Count(), func(i int, elem interface{}) error {
+		b, ok := elem.(byte)
+		if !ok {
+			return fmt.Errorf("vector: element %d is not a byte: %T", i, elem)
+		}
+		result = append(result, b)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
 // Map returns a new vector with the elements of the current vector after
 // applying the given map function.
 func (v *Vector) Map(f func(interface{}) interface{}) *Vector {
 	result := New()
-	for i := 0; i < int(v.count); i++ {
+	for i := 0; i < v.Count(); i++ {
 		result = result.Append(f(v.Get(i)))
 	}
 	return result
@@ -198,7 +777,7 @@ func (v *Vector) Map(f func(interface{}) interface{}) *Vector {
 // satisfy the given filter function.
 func (v *Vector) Filter(f func(interface{}) bool) *Vector {
 	result := New()
-	for i := 0; i < int(v.count); i++ {
+	for i := 0; i < v.Count(); i++ {
 		elem := v.Get(i)
 		if f(elem) {
 			result = result.Append(elem)
@@ -207,46 +786,1384 @@ func (v *Vector) Filter(f func(interface{}) bool) *Vector {
 	return result
 }
 
-// Take returns a new vector with the first n elements of this vector.
-func (v *Vector) Take(n int) *Vector {
-	if uint64(n) >= v.count {
-		return v
-	}
+// FilterMap returns a new vector with the results of applying the given
+// function to the elements of the current vector, keeping only the results
+// for which the function returns true.
+func (v *Vector) FilterMap(f func(interface{}) (interface{}, bool)) *Vector {
+	b := newBuilder(0)
+	_ = v.each(0, v.Count(), func(_ int, elem interface{}) error {
+		if elem, ok := f(elem); ok {
+			b.append(elem)
+		}
+		return nil
+	})
+	return b.vector()
+}
 
-	result := New()
-	for i := 0; i < n; i++ {
-		result = result.Append(v.Get(i))
-	}
-	return result
+// FlattenDeep returns a new vector replacing every element that is a vector
+// with its elements, recursively, at any depth. Since vectors are immutable, a
+// vector can never contain itself, so there can be no cycles.
+func (v *Vector) FlattenDeep() *Vector {
+	b := newBuilder(v.Count())
+	flattenInto(b, v)
+	return b.vector()
 }
 
-// Drop returns a new vector with all the elements in this vector dropping the
-// first n elements.
-func (v *Vector) Drop(n int) *Vector {
-	if n < 0 {
-		panic("cannot drop less than 0 items")
-	}
+func flattenInto(b *builder, v *Vector) {
+	_ = v.each(0, v.Count(), func(_ int, elem interface{}) error {
+		if inner, ok := elem.(*Vector); ok {
+			flattenInto(b, inner)
+		} else {
+			b.append(elem)
+		}
+		return nil
+	})
+}
 
-	if uint64(v.start+n) >= v.count {
-		return New()
-	}
+// FlattenJoin returns a new vector with the elements of every vector in a
+// vector of vectors, in order, with sep between the elements of every two
+// adjacent vectors, as strings.Join does with strings. Inner vectors are not
+// flattened. An error is returned if any element is not a vector.
+func (v *Vector) FlattenJoin(sep interface{}) (*Vector, error) {
+	b := newBuilder(v.Count())
+	err := v.each(0, v.Count(), func(i int, elem interface{}) error {
+		inner, ok := elem.(*Vector)
+		if !ok {
+			return fmt.Errorf("vector: element %d is not a vector: %T", i, elem)
+		}
 
-	return &Vector{
-		v.count,
-		v.shift,
-		v.root,
-		v.tail,
-		v.start + n,
+		if i > 0 {
+			b.append(sep)
+		}
+		return inner.each(0, inner.Count(), func(_ int, elem interface{}) error {
+			b.append(elem)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
 	}
+	return b.vector(), nil
 }
 
-// String returns a string representation of the persistent vector.
+// IndexedElem is an element of a vector along with its index.
+type IndexedElem struct {
+	Index int
+	Value interface{}
+}
+
+// WithIndex returns a new vector where every element is an IndexedElem with
+// the index and value of the element at that position.
+func (v *Vector) WithIndex() *Vector {
+	b := newBuilder(v.Count())
+	_ = v.each(0, v.Count(), func(i int, elem interface{}) error {
+		b.append(IndexedElem{i, elem})
+		return nil
+	})
+	return b.vector()
+}
+
+// Dedupe returns a new vector where every run of consecutive elements that
+// are equal, according to reflect.DeepEqual, is collapsed into its first
+// element. Equal elements that are not adjacent are kept.
+func (v *Vector) Dedupe() *Vector {
+	return v.DedupeFunc(reflect.DeepEqual)
+}
+
+// DedupeFunc returns a new vector where every run of consecutive elements
+// that are equal, according to the given function, is collapsed into its first
+// element. Equal elements that are not adjacent are kept.
+func (v *Vector) DedupeFunc(eq EqualFn) *Vector {
+	b := newBuilder(v.Count())
+	var last interface{}
+	_ = v.each(0, v.Count(), func(i int, elem interface{}) error {
+		if i == 0 || !eq(last, elem) {
+			b.append(elem)
+			last = elem
+		}
+		return nil
+	})
+	return b.vector()
+}
+
+// Run is a number of consecutive repetitions of an element.
+type Run struct {
+	Value interface{}
+	Count int
+}
+
+// RunLengthEncode returns a new vector with a Run for every run of
+// consecutive equal elements of the vector, according to reflect.DeepEqual.
+func (v *Vector) RunLengthEncode() *Vector {
+	b := newBuilder(0)
+	var run Run
+	_ = v.each(0, v.Count(), func(i int, elem interface{}) error {
+		if i > 0 && reflect.DeepEqual(run.Value, elem) {
+			run.Count++
+			return nil
+		}
+
+		if i > 0 {
+			b.append(run)
+		}
+		run = Run{elem, 1}
+		return nil
+	})
+
+	if run.Count > 0 {
+		b.append(run)
+	}
+	return b.vector()
+}
+
+// RunLengthDecode returns a new vector with the elements of a vector of Run
+// elements, such as the ones returned by RunLengthEncode, repeated as many
+// times as their runs say. All elements of the vector must be runs.
+func (v *Vector) RunLengthDecode() (*Vector, error) {
+	b := newBuilder(v.Count())
+	err := v.each(0, v.Count(), func(i int, elem interface{}) error {
+		run, ok := elem.(Run)
+		if !ok {
+			return fmt.Errorf("vector: element %d is not a run: %T", i, elem)
+		}
+
+		for j := 0; j < run.Count; j++ {
+			b.append(run.Value)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return b.vector(), nil
+}
+
+// ChunkBy returns a new vector of vectors with the consecutive elements of the
+// vector that have the same key, according to reflect.DeepEqual. A new chunk
+// starts every time the key changes, so elements with the same key that are not
+// adjacent end up in different chunks.
+func (v *Vector) ChunkBy(key func(interface{}) interface{}) *Vector {
+	chunks := newBuilder(0)
+	var chunk *builder
+	var last interface{}
+	_ = v.each(0, v.Count(), func(i int, elem interface{}) error {
+		k := key(elem)
+		if i == 0 || !reflect.DeepEqual(last, k) {
+			if chunk != nil {
+				chunks.append(chunk.vector())
+			}
+			chunk = newBuilder(0)
+			last = k
+		}
+		chunk.append(elem)
+		return nil
+	})
+
+	if chunk != nil {
+		chunks.append(chunk.vector())
+	}
+	return chunks.vector()
+}
+
+// Sparse returns the elements of the vector that are not nil nor the zero value
+// of their type, keyed by their index.
+func (v *Vector) Sparse() map[int]interface{} {
+	m := make(map[int]interface{})
+	_ = v.each(0, v.Count(), func(i int, elem interface{}) error {
+		if !isZero(elem) {
+			m[i] = elem
+		}
+		return nil
+	})
+	return m
+}
+
+func isZero(elem interface{}) bool {
+	if elem == nil {
+		return true
+	}
+	return reflect.DeepEqual(elem, reflect.Zero(reflect.TypeOf(elem)).Interface())
+}
+
+// Unzip returns two new vectors with the results of splitting each element of
+// the current vector in two using the given function.
+func (v *Vector) Unzip(f func(interface{}) (interface{}, interface{})) (*Vector, *Vector) {
+	n := v.Count()
+	left, right := newBuilder(n), newBuilder(n)
+	_ = v.each(0, n, func(_ int, elem interface{}) error {
+		a, b := f(elem)
+		left.append(a)
+		right.append(b)
+		return nil
+	})
+	return left.vector(), right.vector()
+}
+
+// IsMonotonic reports whether the elements of the vector are in non-decreasing
+// and non-increasing order, according to the given compare function, which
+// returns a negative number if a < b, zero if a == b and a positive number if
+// a > b. Empty vectors and vectors with a single element are both.
+func (v *Vector) IsMonotonic(cmp func(a, b interface{}) int) (increasing bool, decreasing bool) {
+	increasing, decreasing = true, true
+	var prev interface{}
+	_ = v.each(0, v.Count(), func(i int, elem interface{}) error {
+		if i > 0 {
+			c := cmp(prev, elem)
+			if c > 0 {
+				increasing = false
+			} else if c < 0 {
+				decreasing = false
+			}
+
+			if !increasing && !decreasing {
+				return ErrStop
+			}
+		}
+		prev = elem
+		return nil
+	})
+	return increasing, decreasing
+}
+
+// IsPalindrome reports whether the vector has the same elements when read
+// forwards and backwards. Elements are compared using the given function or
+// reflect.DeepEqual if the function is nil. Empty vectors are palindromes.
+func (v *Vector) IsPalindrome(eq EqualFn) bool {
+	if eq == nil {
+		eq = reflect.DeepEqual
+	}
+
+	n := v.Count()
+	for i := 0; i < n/2; i++ {
+		if !eq(v.Get(i), v.Get(n-1-i)) {
+			return false
+		}
+	}
+	return true
+}
+
+// FirstInvalid returns the index of the first element of the vector that is
+// not valid according to the given function. If all elements are valid, it
+// returns -1 and false.
+func (v *Vector) FirstInvalid(valid func(interface{}) bool) (int, bool) {
+	idx := -1
+	_ = v.each(0, v.Count(), func(i int, elem interface{}) error {
+		if !valid(elem) {
+			idx = i
+			return ErrStop
+		}
+		return nil
+	})
+	return idx, idx >= 0
+}
+
+// IndicesOf returns the indexes of all the elements of the vector that satisfy
+// the given function, in ascending order.
+func (v *Vector) IndicesOf(f func(interface{}) bool) []int {
+	var indices []int
+	_ = v.each(0, v.Count(), func(i int, elem interface{}) error {
+		if f(elem) {
+			indices = append(indices, i)
+		}
+		return nil
+	})
+	return indices
+}
+
+// Select returns a new vector with the elements at the given indexes, in the
+// order they are given. Indexes may be repeated and, as with Get, negative
+// indexes count from the end of the vector. An error is returned if any index
+// is out of bounds.
+func (v *Vector) Select(indices []int) (*Vector, error) {
+	n := v.Count()
+	b := newBuilder(len(indices))
+	for _, i := range indices {
+		if i < -n || i >= n {
+			return nil, fmt.Errorf("vector: index %d out of bounds of a vector "+
+				"with %d elements", i, n)
+		}
+
+		if i < 0 {
+			i += n
+		}
+		b.append(v.Get(i))
+	}
+	return b.vector(), nil
+}
+
+// Scatter returns a new vector with n elements, in which the element at every
+// index i of the vector is placed at position perm[i], and the positions that
+// no element is placed at are nil. It's the reverse of Select. An error is
+// returned if perm does not have as many positions as the vector has
+// elements, or any position is out of bounds or repeated. If n is less than 0,
+// it will panic.
+func (v *Vector) Scatter(perm []int, n int) (*Vector, error) {
+	if n < 0 {
+		panic("cannot create a vector with less than 0 items")
+	}
+
+	if len(perm) != v.Count() {
+		return nil, fmt.Errorf("vector: cannot scatter %d elements to %d positions",
+			v.Count(), len(perm))
+	}
+
+	elems := make([]interface{}, n)
+	filled := make([]bool, n)
+	err := v.each(0, v.Count(), func(i int, elem interface{}) error {
+		pos := perm[i]
+		if pos < 0 || pos >= n {
+			return fmt.Errorf("vector: position %d out of bounds of a vector "+
+				"with %d elements", pos, n)
+		}
+
+		if filled[pos] {
+			return fmt.Errorf("vector: position %d is repeated", pos)
+		}
+
+		elems[pos] = elem
+		filled[pos] = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return fromSlice(elems), nil
+}
+
+// CountFuncRange returns the number of elements in the range [lo, hi) that
+// satisfy the given function. If the range is not valid, it will panic.
+func (v *Vector) CountFuncRange(lo, hi int, f func(interface{}) bool) int {
+	v.checkRange(lo, hi)
+
+	var n int
+	_ = v.each(lo, hi, func(_ int, elem interface{}) error {
+		if f(elem) {
+			n++
+		}
+		return nil
+	})
+	return n
+}
+
+// Histogram returns the number of elements of the vector in each of nBuckets
+// buckets, where the bucket of every element is given by the bucket function.
+// Elements whose bucket is less than 0 or not less than nBuckets are not
+// counted. If nBuckets is less than 0, it will panic.
+func (v *Vector) Histogram(bucket func(interface{}) int, nBuckets int) []int {
+	if nBuckets < 0 {
+		panic("cannot make a histogram with less than 0 buckets")
+	}
+
+	counts := make([]int, nBuckets)
+	_ = v.each(0, v.Count(), func(_ int, elem interface{}) error {
+		if b := bucket(elem); b >= 0 && b < nBuckets {
+			counts[b]++
+		}
+		return nil
+	})
+	return counts
+}
+
+// CDF returns the cumulative distribution of the elements of the vector over
+// the buckets of Histogram, that is, the fraction of the counted elements that
+// fall in each bucket or in any bucket before it. Unless no element is
+// counted, in which case all fractions are 0, the last fraction is always 1.
+func (v *Vector) CDF(bucket func(interface{}) int, nBuckets int) []float64 {
+	counts := v.Histogram(bucket, nBuckets)
+
+	var total int
+	for _, c := range counts {
+		total += c
+	}
+
+	result := make([]float64, nBuckets)
+	if total == 0 {
+		return result
+	}
+
+	var acc int
+	for i, c := range counts {
+		acc += c
+		result[i] = float64(acc) / float64(total)
+	}
+	return result
+}
+
+// checkRange panics if [lo, hi) is not a valid range of the vector.
+func (v *Vector) checkRange(lo, hi int) {
+	if lo < 0 || hi > v.Count() || lo > hi {
+		panic(fmt.Errorf("vector: invalid range [%d, %d) of a vector "+
+			"with %d elements", lo, hi, v.Count()))
+	}
+}
+
+// LongestRun returns the index of the first element and the length of the
+// longest run of consecutive elements that satisfy the given function. If
+// there are several runs with the same length, the first one is returned. If
+// no element satisfies the function, it returns -1 and 0.
+func (v *Vector) LongestRun(f func(interface{}) bool) (start, length int) {
+	start = -1
+	var runStart, runLength int
+	_ = v.each(0, v.Count(), func(i int, elem interface{}) error {
+		if !f(elem) {
+			runLength = 0
+			return nil
+		}
+
+		if runLength == 0 {
+			runStart = i
+		}
+		runLength++
+		if runLength > length {
+			start, length = runStart, runLength
+		}
+		return nil
+	})
+	return start, length
+}
+
+// ContainsAll returns whether every element of other is in the vector,
+// regardless of their order or how many times they appear. Elements are
+// compared using the given function or reflect.DeepEqual if it's nil. With a
+// nil function, booleans, numbers and strings are looked up in a set, so it
+// takes O(n+m) time instead of O(n*m).
+func (v *Vector) ContainsAll(other *Vector, eq EqualFn) bool {
+	contains := v.membership(eq)
+	all := true
+	_ = other.each(0, other.Count(), func(_ int, elem interface{}) error {
+		if !contains(elem) {
+			all = false
+			return ErrStop
+		}
+		return nil
+	})
+	return all
+}
+
+// ContainsAny returns whether any element of other is in the vector. Elements
+// are compared in the same way as in ContainsAll.
+func (v *Vector) ContainsAny(other *Vector, eq EqualFn) bool {
+	contains := v.membership(eq)
+	found := false
+	_ = other.each(0, other.Count(), func(_ int, elem interface{}) error {
+		if contains(elem) {
+			found = true
+			return ErrStop
+		}
+		return nil
+	})
+	return found
+}
+
+// membership returns a function that reports whether an element is in the
+// vector according to eq or, if it's nil, reflect.DeepEqual. For a nil eq,
+// scalar elements are kept in a set, since for them == and reflect.DeepEqual
+// agree, and only the rest need to be compared one by one.
+func (v *Vector) membership(eq EqualFn) func(interface{}) bool {
+	if eq != nil {
+		return func(x interface{}) bool {
+			found := false
+			_ = v.each(0, v.Count(), func(_ int, elem interface{}) error {
+				if eq(elem, x) {
+					found = true
+					return ErrStop
+				}
+				return nil
+			})
+			return found
+		}
+	}
+
+	set := make(map[interface{}]struct{})
+	var rest []interface{}
+	_ = v.each(0, v.Count(), func(_ int, elem interface{}) error {
+		if isScalar(elem) {
+			set[elem] = struct{}{}
+		} else {
+			rest = append(rest, elem)
+		}
+		return nil
+	})
+
+	return func(x interface{}) bool {
+		if isScalar(x) {
+			_, ok := set[x]
+			return ok
+		}
+
+		for _, elem := range rest {
+			if reflect.DeepEqual(elem, x) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// isScalar returns whether the given value is a boolean, number or string.
+func isScalar(x interface{}) bool {
+	if x == nil {
+		return false
+	}
+
+	switch reflect.TypeOf(x).Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return true
+	default:
+		return false
+	}
+}
+
+// CountSubsequence returns the number of non-overlapping occurrences of the
+// elements of sub, in order and contiguous, in the vector. Elements are
+// compared using the given function or reflect.DeepEqual if it's nil. An empty
+// sub never occurs.
+func (v *Vector) CountSubsequence(sub *Vector, eq EqualFn) int {
+	if eq == nil {
+		eq = reflect.DeepEqual
+	}
+
+	m := sub.Count()
+	if m == 0 {
+		return 0
+	}
+
+	var n int
+	for i := 0; i+m <= v.Count(); {
+		if v.hasSubsequenceAt(i, sub, eq) {
+			n++
+			i += m
+		} else {
+			i++
+		}
+	}
+	return n
+}
+
+// IndexOfSubsequence returns the index of the first occurrence of the
+// elements of sub, in order and contiguous, in the vector, or -1 if they don't
+// occur. An empty sub occurs at index 0. Elements are compared using the given
+// function or reflect.DeepEqual if it's nil. Every position of the vector is
+// checked, so it takes O(n*m) time.
+func (v *Vector) IndexOfSubsequence(sub *Vector, eq EqualFn) int {
+	if eq == nil {
+		eq = reflect.DeepEqual
+	}
+
+	for i := 0; i+sub.Count() <= v.Count(); i++ {
+		if v.hasSubsequenceAt(i, sub, eq) {
+			return i
+		}
+	}
+	return -1
+}
+
+// hasSubsequenceAt returns whether the elements of sub are in the vector
+// starting at the given index.
+func (v *Vector) hasSubsequenceAt(i int, sub *Vector, eq EqualFn) bool {
+	for j := 0; j < sub.Count(); j++ {
+		if !eq(v.Get(i+j), sub.Get(j)) {
+			return false
+		}
+	}
+	return true
+}
+
+// CountLeading returns the number of elements at the beginning of the vector
+// that satisfy the given function.
+func (v *Vector) CountLeading(f func(interface{}) bool) int {
+	var n int
+	_ = v.each(0, v.Count(), func(_ int, elem interface{}) error {
+		if !f(elem) {
+			return ErrStop
+		}
+		n++
+		return nil
+	})
+	return n
+}
+
+// CountTrailing returns the number of elements at the end of the vector that
+// satisfy the given function.
+func (v *Vector) CountTrailing(f func(interface{}) bool) int {
+	var n int
+	for i := v.Count() - 1; i >= 0 && f(v.Get(i)); i-- {
+		n++
+	}
+	return n
+}
+
+// Shuffle returns a new vector with the elements of the current vector in a
+// random order, using rng as the source of randomness.
+func (v *Vector) Shuffle(rng *rand.Rand) *Vector {
+	elems := v.Slice()
+	for i := len(elems) - 1; i > 0; i-- {
+		j := rng.Intn(i + 1)
+		elems[i], elems[j] = elems[j], elems[i]
+	}
+	return fromSlice(elems)
+}
+
+// MovingReduce returns a new vector with the result of folding each sliding
+// window of the given size with the given function, starting with init as
+// the accumulated value. The resulting vector has Count()-size+1 elements, or
+// none if the vector has less than size elements.
+func (v *Vector) MovingReduce(
+	size int,
+	init interface{},
+	f func(acc, elem interface{}) interface{},
+) *Vector {
+	b := newBuilder(v.Count() - size + 1)
+	_ = v.windows(size, func(window []interface{}) error {
+		acc := init
+		for _, e := range window {
+			acc = f(acc, e)
+		}
+		b.append(acc)
+		return nil
+	})
+	return b.vector()
+}
+
+// CircularWindow calls f with every sliding window of the given size of the
+// vector, treating it as a ring buffer. That is, the last windows wrap around
+// and include elements from the beginning, so there are as many windows as
+// elements. Windows share their backing array, so they must not be retained
+// after f returns. As with Range, ErrStop may be returned to stop the
+// iteration and any other error will terminate the iteration and will be
+// returned.
+func (v *Vector) CircularWindow(size int, f func(window []interface{}) error) error {
+	if size < 1 {
+		panic("window size cannot be less than 1")
+	}
+
+	elems := v.Slice()
+	n := len(elems)
+	if n == 0 {
+		return nil
+	}
+
+	for i := 0; i < size-1; i++ {
+		elems = append(elems, elems[i%n])
+	}
+
+	for i := 0; i < n; i++ {
+		if err := f(elems[i : i+size]); err != nil {
+			if err == ErrStop {
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// windows calls f with every sliding window of the given size. Windows share
+// their backing array, so they must not be retained after f returns.
+func (v *Vector) windows(size int, f func(window []interface{}) error) error {
+	if size < 1 {
+		panic("window size cannot be less than 1")
+	}
+
+	elems := v.Slice()
+	for i := 0; i+size <= len(elems); i++ {
+		if err := f(elems[i : i+size]); err != nil {
+			if err == ErrStop {
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// TopN returns a new vector with the n largest elements of the vector in
+// descending order, according to the given less function. If n is greater
+// than the number of elements, all elements are returned.
+func (v *Vector) TopN(n int, less func(a, b interface{}) bool) *Vector {
+	return fromSlice(v.topN(n, less))
+}
+
+// BottomN returns a new vector with the n smallest elements of the vector in
+// ascending order, according to the given less function. If n is greater
+// than the number of elements, all elements are returned.
+func (v *Vector) BottomN(n int, less func(a, b interface{}) bool) *Vector {
+	return fromSlice(v.topN(n, func(a, b interface{}) bool {
+		return less(b, a)
+	}))
+}
+
+// MinMax returns the smallest and largest elements of the vector according to
+// the given less function, comparing elements in pairs so that only about 3n/2
+// comparisons are needed. If the vector is empty, ok will be false.
+func (v *Vector) MinMax(less func(a, b interface{}) bool) (min, max interface{}, ok bool) {
+	n := v.Count()
+	if n == 0 {
+		return nil, nil, false
+	}
+
+	c := &cursor{v: v}
+	min, _ = c.next()
+	max = min
+	i := 1
+	for ; i+1 < n; i += 2 {
+		a, _ := c.next()
+		b, _ := c.next()
+		if less(b, a) {
+			a, b = b, a
+		}
+		if less(a, min) {
+			min = a
+		}
+		if less(max, b) {
+			max = b
+		}
+	}
+
+	if i < n {
+		last, _ := c.next()
+		if less(last, min) {
+			min = last
+		} else if less(max, last) {
+			max = last
+		}
+	}
+
+	return min, max, true
+}
+
+// ArgMax returns the index of the largest element of the vector according to
+// the given less function. If there are several, the index of the first one is
+// returned. If the vector is empty, it returns -1 and false.
+func (v *Vector) ArgMax(less func(a, b interface{}) bool) (int, bool) {
+	return v.argBest(func(elem, best interface{}) bool {
+		return less(best, elem)
+	})
+}
+
+// ArgMin returns the index of the smallest element of the vector according to
+// the given less function. If there are several, the index of the first one is
+// returned. If the vector is empty, it returns -1 and false.
+func (v *Vector) ArgMin(less func(a, b interface{}) bool) (int, bool) {
+	return v.argBest(less)
+}
+
+// argBest returns the index of the first element of the vector that no other
+// element is better than, according to the given function.
+func (v *Vector) argBest(better func(elem, best interface{}) bool) (int, bool) {
+	idx := -1
+	var best interface{}
+	_ = v.each(0, v.Count(), func(i int, elem interface{}) error {
+		if idx < 0 || better(elem, best) {
+			idx, best = i, elem
+		}
+		return nil
+	})
+	return idx, idx >= 0
+}
+
+// CumulativeMax returns a new vector where each element is the largest of the
+// elements of the vector up to that position, according to the given less
+// function.
+func (v *Vector) CumulativeMax(less func(a, b interface{}) bool) *Vector {
+	return v.scan(func(max, elem interface{}) interface{} {
+		if less(max, elem) {
+			return elem
+		}
+		return max
+	})
+}
+
+// CumulativeMin returns a new vector where each element is the smallest of
+// the elements of the vector up to that position, according to the given less
+// function.
+func (v *Vector) CumulativeMin(less func(a, b interface{}) bool) *Vector {
+	return v.scan(func(min, elem interface{}) interface{} {
+		if less(elem, min) {
+			return elem
+		}
+		return min
+	})
+}
+
+// scan returns a new vector where the first element is the first element of
+// the vector and every other element is the result of folding the previous
+// result with the element at that position.
+func (v *Vector) scan(f func(acc, elem interface{}) interface{}) *Vector {
+	b := newBuilder(v.Count())
+	var acc interface{}
+	_ = v.each(0, v.Count(), func(i int, elem interface{}) error {
+		if i == 0 {
+			acc = elem
+		} else {
+			acc = f(acc, elem)
+		}
+		b.append(acc)
+		return nil
+	})
+	return b.vector()
+}
+
+// KthSmallest returns the k-th smallest element of the vector according to
+// the given less function, where k starts at 0 for the smallest element. It
+// uses quickselect on a copy of the elements, which takes O(n) time on
+// average. If k is out of bounds, ok will be false.
+func (v *Vector) KthSmallest(k int, less func(a, b interface{}) bool) (elem interface{}, ok bool) {
+	if k < 0 || k >= v.Count() {
+		return nil, false
+	}
+	return quickselect(v.Slice(), k, less), true
+}
+
+// quickselect returns the k-th smallest element of elems, which are
+// reordered in the process.
+func quickselect(elems []interface{}, k int, less func(a, b interface{}) bool) interface{} {
+	lo, hi := 0, len(elems)-1
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		elems[mid], elems[hi] = elems[hi], elems[mid]
+		pivot := elems[hi]
+
+		p := lo
+		for i := lo; i < hi; i++ {
+			if less(elems[i], pivot) {
+				elems[i], elems[p] = elems[p], elems[i]
+				p++
+			}
+		}
+		elems[p], elems[hi] = elems[hi], elems[p]
+
+		switch {
+		case k < p:
+			hi = p - 1
+		case k > p:
+			lo = p + 1
+		default:
+			return elems[k]
+		}
+	}
+	return elems[k]
+}
+
+// topN returns the n largest elements in descending order using a bounded
+// heap, which takes O(count*log(n)) time.
+func (v *Vector) topN(n int, less func(a, b interface{}) bool) []interface{} {
+	count := v.Count()
+	if n > count {
+		n = count
+	}
+	if n <= 0 {
+		return nil
+	}
+
+	h := &elemHeap{make([]interface{}, 0, n), less}
+	for i := 0; i < count; i++ {
+		elem := v.Get(i)
+		if h.Len() < n {
+			heap.Push(h, elem)
+		} else if less(h.elems[0], elem) {
+			h.elems[0] = elem
+			heap.Fix(h, 0)
+		}
+	}
+
+	result := make([]interface{}, n)
+	for i := n - 1; i >= 0; i-- {
+		result[i] = heap.Pop(h)
+	}
+	return result
+}
+
+// elemHeap is a min-heap of elements implementing heap.Interface.
+type elemHeap struct {
+	elems []interface{}
+	less  func(a, b interface{}) bool
+}
+
+func (h *elemHeap) Len() int           { return len(h.elems) }
+func (h *elemHeap) Less(i, j int) bool { return h.less(h.elems[i], h.elems[j]) }
+func (h *elemHeap) Swap(i, j int)      { h.elems[i], h.elems[j] = h.elems[j], h.elems[i] }
+func (h *elemHeap) Push(x interface{}) { h.elems = append(h.elems, x) }
+
+func (h *elemHeap) Pop() interface{} {
+	last := h.elems[len(h.elems)-1]
+	h.elems = h.elems[:len(h.elems)-1]
+	return last
+}
+
+// Take returns a new vector with the first n elements of this vector.
+func (v *Vector) Take(n int) *Vector {
+	if uint64(n) >= v.count {
+		return v
+	}
+
+	result := New()
+	for i := 0; i < n; i++ {
+		result = result.Append(v.Get(i))
+	}
+	return result
+}
+
+// Intersperse returns a new vector with sep inserted between every two
+// elements of the vector.
+func (v *Vector) Intersperse(sep interface{}) *Vector {
+	n := v.Count()
+	if n < 2 {
+		return v
+	}
+
+	b := newBuilder(2*n - 1)
+	_ = v.each(0, n, func(i int, elem interface{}) error {
+		if i > 0 {
+			b.append(sep)
+		}
+		b.append(elem)
+		return nil
+	})
+	return b.vector()
+}
+
+// PadLeft returns a new vector with fill prepended as many times as needed
+// for the vector to have n elements. If the vector already has n or more
+// elements, it is returned unchanged.
+func (v *Vector) PadLeft(n int, fill interface{}) *Vector {
+	count := v.Count()
+	if count >= n {
+		return v
+	}
+
+	b := newBuilder(n)
+	for i := count; i < n; i++ {
+		b.append(fill)
+	}
+	_ = v.each(0, count, func(_ int, elem interface{}) error {
+		b.append(elem)
+		return nil
+	})
+	return b.vector()
+}
+
+// PadRight returns a new vector with fill appended as many times as needed
+// for the vector to have n elements. If the vector already has n or more
+// elements, it is returned unchanged.
+func (v *Vector) PadRight(n int, fill interface{}) *Vector {
+	result := v
+	for i := v.Count(); i < n; i++ {
+		result = result.Append(fill)
+	}
+	return result
+}
+
+// DropEvery returns a new vector without every n-th element of the vector,
+// that is, without the elements at indexes n-1, 2n-1, 3n-1 and so on. If n is
+// less than 1, it will panic.
+func (v *Vector) DropEvery(n int) *Vector {
+	if n < 1 {
+		panic("cannot drop every less than 1 items")
+	}
+
+	b := newBuilder(v.Count() - v.Count()/n)
+	_ = v.each(0, v.Count(), func(i int, elem interface{}) error {
+		if (i+1)%n != 0 {
+			b.append(elem)
+		}
+		return nil
+	})
+	return b.vector()
+}
+
+// KeepEvery returns a new vector with only every n-th element of the vector,
+// that is, the elements at indexes n-1, 2n-1, 3n-1 and so on, which are the
+// ones DropEvery drops. If n is less than 1, it will panic.
+func (v *Vector) KeepEvery(n int) *Vector {
+	if n < 1 {
+		panic("cannot keep every less than 1 items")
+	}
+
+	b := newBuilder(v.Count() / n)
+	_ = v.each(0, v.Count(), func(i int, elem interface{}) error {
+		if (i+1)%n == 0 {
+			b.append(elem)
+		}
+		return nil
+	})
+	return b.vector()
+}
+
+// Drop returns a new vector with all the elements in this vector dropping the
+// first n elements.
+func (v *Vector) Drop(n int) *Vector {
+	if n < 0 {
+		panic("cannot drop less than 0 items")
+	}
+
+	if uint64(v.start+n) >= v.count {
+		return New()
+	}
+
+	return &Vector{
+		v.count,
+		v.shift,
+		v.root,
+		v.tail,
+		v.start + n,
+	}
+}
+
+// DropRange returns a new vector without the elements in the range [lo, hi).
+// As with Get, negative bounds count from the end of the vector. If the range
+// is not valid, it will panic.
+func (v *Vector) DropRange(lo, hi int) *Vector {
+	n := v.Count()
+	lo, hi = normalizeIndex(lo, n), normalizeIndex(hi, n)
+	v.checkRange(lo, hi)
+
+	if lo == hi {
+		return v
+	}
+
+	if lo == 0 {
+		return v.sub(hi, n)
+	}
+
+	b := newBuilder(n - (hi - lo))
+	appendElem := func(_ int, elem interface{}) error {
+		b.append(elem)
+		return nil
+	}
+	_ = v.each(0, lo, appendElem)
+	_ = v.each(hi, n, appendElem)
+	return b.vector()
+}
+
+// KeepRange returns a new vector with only the elements in the range [lo, hi).
+// Unlike Drop, the result is always rebuilt and does not share any node with
+// the vector, so the elements outside the range can be garbage collected once
+// the vector is no longer used. As with Get, negative bounds count from the
+// end of the vector. If the range is not valid, it will panic.
+func (v *Vector) KeepRange(lo, hi int) *Vector {
+	n := v.Count()
+	lo, hi = normalizeIndex(lo, n), normalizeIndex(hi, n)
+	v.checkRange(lo, hi)
+
+	b := newBuilder(hi - lo)
+	_ = v.each(lo, hi, func(_ int, elem interface{}) error {
+		b.append(elem)
+		return nil
+	})
+	return b.vector()
+}
+
+// normalizeIndex returns the given index of a vector with n elements, counting
+// from the end if it's negative.
+func normalizeIndex(i, n int) int {
+	if i < 0 {
+		return i + n
+	}
+	return i
+}
+
+// RangeByValue returns a new vector with the elements of the vector that are
+// between lo and hi, both included, according to the given compare function,
+// which returns a negative number if a < b, zero if a == b and a positive
+// number if a > b. The vector must be sorted in ascending order according to
+// the same function, since the bounds are found using binary search.
+func (v *Vector) RangeByValue(lo, hi interface{}, cmp func(a, b interface{}) int) *Vector {
+	n := v.Count()
+	from := sort.Search(n, func(i int) bool {
+		return cmp(v.Get(i), lo) >= 0
+	})
+	to := sort.Search(n, func(i int) bool {
+		return cmp(v.Get(i), hi) > 0
+	})
+
+	if from >= to {
+		return New()
+	}
+	return v.sub(from, to)
+}
+
+// PartitionPoint returns the index of the first element of the vector that
+// does not satisfy pred, or the number of elements if all of them do. The
+// vector must be partitioned according to pred, with all the elements that
+// satisfy it before all the ones that don't, since the index is found using
+// binary search.
+func (v *Vector) PartitionPoint(pred func(interface{}) bool) int {
+	return sort.Search(v.Count(), func(i int) bool {
+		return !pred(v.Get(i))
+	})
+}
+
+// SplitN returns n vectors with the elements of the vector, in order, whose
+// number of elements differs at most by one, with the longer ones first. The
+// parts share the structure of the vector, reusing all its leaves that are
+// entirely inside them. If n is less than 1, it will panic.
+func (v *Vector) SplitN(n int) []*Vector {
+	if n < 1 {
+		panic("cannot split in less than 1 part")
+	}
+
+	count := v.Count()
+	size, rest := count/n, count%n
+	parts := make([]*Vector, n)
+	var lo int
+	for i := range parts {
+		hi := lo + size
+		if i < rest {
+			hi++
+		}
+		if hi >= count {
+			parts[i] = v.sub(lo, hi)
+		} else {
+			parts[i] = v.subLeaves(lo, hi)
+		}
+		lo = hi
+	}
+	return parts
+}
+
+// Reshape returns a new vector of vectors, each one with cols elements of the
+// vector, in order, so the vector can be used as a matrix. The number of
+// elements in the vector must be a multiple of cols.
+func (v *Vector) Reshape(cols int) (*Vector, error) {
+	if cols < 1 {
+		return nil, fmt.Errorf("vector: cannot reshape with %d columns", cols)
+	}
+
+	count := v.Count()
+	if count%cols != 0 {
+		return nil, fmt.Errorf("vector: cannot reshape a vector with %d "+
+			"elements into rows of %d elements", count, cols)
+	}
+
+	b := newBuilder(count / cols)
+	for i := 0; i < count; i += cols {
+		b.append(v.sub(i, i+cols).Canonical())
+	}
+	return b.vector(), nil
+}
+
+// Grid returns a new vector of rows vectors, each one with cols elements of the
+// vector, in order. Only the first rows*cols elements are used, and the vector
+// must have at least that many elements.
+func (v *Vector) Grid(rows, cols int) (*Vector, error) {
+	if rows < 1 || cols < 1 {
+		return nil, fmt.Errorf("vector: cannot make a grid of %dx%d", rows, cols)
+	}
+
+	if count := v.Count(); count < rows*cols {
+		return nil, fmt.Errorf("vector: cannot make a grid of %dx%d with "+
+			"%d elements", rows, cols, count)
+	}
+
+	return v.sub(0, rows*cols).Reshape(cols)
+}
+
+// Transpose returns the transposed matrix of a vector of vectors, that is, a
+// new vector of vectors where the element at column j of row i is the element
+// at column i of row j of the vector. All elements of the vector must be
+// vectors with the same number of elements.
+func (v *Vector) Transpose() (*Vector, error) {
+	rows := v.Count()
+	if rows == 0 {
+		return New(), nil
+	}
+
+	matrix := make([]*Vector, rows)
+	var cols int
+	for i := range matrix {
+		row, ok := v.Get(i).(*Vector)
+		if !ok {
+			return nil, fmt.Errorf("vector: row %d is not a vector: %T", i, v.Get(i))
+		}
+
+		if i == 0 {
+			cols = row.Count()
+		} else if row.Count() != cols {
+			return nil, fmt.Errorf("vector: row %d has %d elements, expected %d",
+				i, row.Count(), cols)
+		}
+		matrix[i] = row
+	}
+
+	b := newBuilder(cols)
+	for j := 0; j < cols; j++ {
+		col := newBuilder(rows)
+		for _, row := range matrix {
+			col.append(row.Get(j))
+		}
+		b.append(col.vector())
+	}
+	return b.vector(), nil
+}
+
+// sub returns a vector with the elements in the range [lo, hi). If the range
+// reaches the end of the vector, the result shares the structure of the
+// vector.
+func (v *Vector) sub(lo, hi int) *Vector {
+	if hi >= v.Count() {
+		if lo == 0 {
+			return v
+		}
+		return v.Drop(lo)
+	}
+
+	b := newBuilder(hi - lo)
+	_ = v.each(lo, hi, func(_ int, elem interface{}) error {
+		b.append(elem)
+		return nil
+	})
+	return b.vector()
+}
+
+// subLeaves returns a new vector with the elements in the range [lo, hi) that
+// reuses the leaves of the vector entirely inside the range. The result starts
+// at the beginning of the leaf with the element at lo, like a Drop view, so
+// the leaves keep the same positions as in the vector.
+func (v *Vector) subLeaves(lo, hi int) *Vector {
+	if lo >= hi {
+		return New()
+	}
+
+	start := uint64(v.start + lo)
+	end := uint64(v.start + hi)
+	base := start &^ uint64(vectorMask)
+	b := newBuilder(int(end - base))
+	for key := base; key < end; key += uint64(vectorWidth) {
+		leaf := v.leafFor(key)
+		if key+uint64(vectorWidth) <= end && len(leaf.values) == int(vectorWidth) {
+			b.appendLeaf(leaf)
+			continue
+		}
+
+		for _, elem := range leaf.values[:end-key] {
+			b.append(elem)
+		}
+	}
+
+	result := b.vector()
+	result.start = int(start - base)
+	return result
+}
+
+// String returns a string representation of the persistent vector.
 func (v *Vector) String() string {
-	var items []string
-	for i := uint64(0); i < v.count-uint64(v.start); i++ {
-		items = append(items, fmt.Sprint(v.Get(int(i))))
+	return "[" + v.Join(", ", nil) + "]"
+}
+
+// WriteTo writes the same representation of the vector returned by String to
+// w, element by element, without building the whole string in memory. It
+// returns the number of bytes written.
+func (v *Vector) WriteTo(w io.Writer) (int64, error) {
+	var written int64
+	write := func(n int, err error) error {
+		written += int64(n)
+		return err
+	}
+
+	if err := write(io.WriteString(w, "[")); err != nil {
+		return written, err
+	}
+
+	err := v.each(0, v.Count(), func(i int, elem interface{}) error {
+		if i > 0 {
+			if err := write(io.WriteString(w, ", ")); err != nil {
+				return err
+			}
+		}
+		return write(fmt.Fprint(w, elem))
+	})
+	if err != nil {
+		return written, err
+	}
+
+	return written, write(io.WriteString(w, "]"))
+}
+
+// Join returns a string with all the elements of the vector formatted using
+// the given function and separated by sep. If format is nil, fmt.Sprint is
+// used to format the elements.
+func (v *Vector) Join(sep string, format func(interface{}) string) string {
+	if format == nil {
+		format = func(x interface{}) string {
+			return fmt.Sprint(x)
+		}
 	}
-	return fmt.Sprintf("[%s]", strings.Join(items, ", "))
+
+	var sb strings.Builder
+	_ = v.each(0, v.Count(), func(i int, elem interface{}) error {
+		if i > 0 {
+			sb.WriteString(sep)
+		}
+		sb.WriteString(format(elem))
+		return nil
+	})
+	return sb.String()
+}
+
+// CommonPrefix returns a vector with the longest sequence of elements that all
+// the given vectors start with, comparing elements using reflect.DeepEqual.
+// With no vectors, the result is empty.
+func CommonPrefix(vs ...*Vector) *Vector {
+	if len(vs) == 0 {
+		return New()
+	}
+
+	first := vs[0]
+	n := first.Count()
+	for _, v := range vs[1:] {
+		if c := v.Count(); c < n {
+			n = c
+		}
+	}
+
+	cursors := make([]*cursor, len(vs)-1)
+	for i, v := range vs[1:] {
+		cursors[i] = &cursor{v: v}
+	}
+
+	prefix := 0
+	_ = first.each(0, n, func(i int, elem interface{}) error {
+		for _, c := range cursors {
+			if other, _ := c.next(); !reflect.DeepEqual(elem, other) {
+				return ErrStop
+			}
+		}
+		prefix++
+		return nil
+	})
+	return first.sub(0, prefix)
+}
+
+// MergeSorted returns a new vector with the elements of v1 and v2 in the order
+// given by less. Both vectors must already be sorted according to less,
+// otherwise the result is not sorted either. Elements that are equal are taken
+// from v1 before v2, so the merge is stable.
+func MergeSorted(v1, v2 *Vector, less func(a, b interface{}) bool) *Vector {
+	b := newBuilder(v1.Count() + v2.Count())
+	c1, c2 := &cursor{v: v1}, &cursor{v: v2}
+	x, ok1 := c1.next()
+	y, ok2 := c2.next()
+	for ok1 && ok2 {
+		if less(y, x) {
+			b.append(y)
+			y, ok2 = c2.next()
+		} else {
+			b.append(x)
+			x, ok1 = c1.next()
+		}
+	}
+
+	for ; ok1; x, ok1 = c1.next() {
+		b.append(x)
+	}
+	for ; ok2; y, ok2 = c2.next() {
+		b.append(y)
+	}
+	return b.vector()
 }
 
 // Equal returns whether a vector has the same items as another vector.
@@ -261,6 +2178,8 @@ type EqualFn func(a, b interface{}) bool
 
 // EqualFunc returns whether a vector has the same items as another vector
 // using the given function to determine whether they're equal or not.
+// Subtrees shared by both vectors, such as the ones left untouched by Set, are
+// considered equal without comparing their elements.
 func EqualFunc(v1, v2 *Vector, fn EqualFn) bool {
 	len1 := v1.Count()
 	len2 := v2.Count()
@@ -269,6 +2188,12 @@ func EqualFunc(v1, v2 *Vector, fn EqualFn) bool {
 		return false
 	}
 
+	if v1.start == v2.start && v1.count == v2.count && v1.shift == v2.shift {
+		start := uint64(v1.start)
+		return equalNodes(v1.tail, v2.tail, 0, v1.tailOffset(), start, fn) &&
+			equalNodes(v1.root, v2.root, v1.shift, 0, start, fn)
+	}
+
 	for i := 0; i < len1; i++ {
 		a := v1.Get(i)
 		b := v2.Get(i)
@@ -280,6 +2205,91 @@ func EqualFunc(v1, v2 *Vector, fn EqualFn) bool {
 	return true
 }
 
+// equalNodes returns whether two nodes at the same level of two vectors with
+// the same layout are equal, skipping the elements before start. The key of
+// the first element in the nodes is given by base. Nodes that are the same
+// are not compared.
+func equalNodes(a, b *node, shift uint, base, start uint64, fn EqualFn) bool {
+	if a == b {
+		return true
+	}
+
+	for i := range a.values {
+		key := base + uint64(i)<<shift
+		if key+1<<shift <= start {
+			continue
+		}
+
+		if shift == 0 {
+			if !fn(a.values[i], b.values[i]) {
+				return false
+			}
+			continue
+		}
+
+		x, _ := a.values[i].(*node)
+		y, _ := b.values[i].(*node)
+		if x == nil || y == nil {
+			if x != y {
+				return false
+			}
+			continue
+		}
+
+		if !equalNodes(x, y, shift-uint(vectorBits), key, start, fn) {
+			return false
+		}
+	}
+	return true
+}
+
+// SharesStructure returns whether two vectors have any node in common, which
+// means they descend from a common vector through operations such as Set,
+// Append or Drop. The empty nodes shared by all small vectors are not taken
+// into account.
+func SharesStructure(v1, v2 *Vector) bool {
+	nodes := make(map[*node]struct{})
+	v1.walkNodes(func(n *node) bool {
+		nodes[n] = struct{}{}
+		return true
+	})
+
+	var shared bool
+	v2.walkNodes(func(n *node) bool {
+		_, shared = nodes[n]
+		return !shared
+	})
+	return shared
+}
+
+// walkNodes calls f with every node of the vector, including the tail and
+// excluding the empty ones, until f returns false.
+func (v *Vector) walkNodes(f func(*node) bool) {
+	if v.count == 0 || !f(v.tail) || v.root == emptyNode {
+		return
+	}
+	walkNode(v.root, v.shift, f)
+}
+
+func walkNode(n *node, shift uint, f func(*node) bool) bool {
+	if !f(n) {
+		return false
+	}
+
+	if shift == 0 {
+		return true
+	}
+
+	for _, child := range n.values {
+		if child, ok := child.(*node); ok {
+			if !walkNode(child, shift-uint(vectorBits), f) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
 const (
 	vectorBits  uint32 = 5
 	vectorWidth uint32 = 1 << 5
@@ -305,9 +2315,14 @@ var (
 	emptyVector = &Vector{0, 5, emptyNode, &node{nil}, 0}
 )
 
-// newPath creates a new path all the way through a branch inserting at the leftmost leaf.
-func newPath(n *node) *node {
+// newPath creates a new path all the way through a branch from the given
+// level inserting at the leftmost leaf.
+func newPath(shift uint, n *node) *node {
+	if shift == 0 {
+		return n
+	}
+
 	node := &node{make([]interface{}, vectorWidth)}
-	node.values[0] = n
+	node.values[0] = newPath(shift-uint(vectorBits), n)
 	return node
 }
@@ -1,12 +1,97 @@
 package vector
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"math/rand"
+	"sort"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 )
 
+func TestFromArray(t *testing.T) {
+	require := require.New(t)
+
+	v, err := FromArray([3]int{1, 2, 3})
+	require.NoError(err)
+	require.True(Equal(New(1, 2, 3), v))
+
+	v, err = FromArray([0]string{})
+	require.NoError(err)
+	require.Equal(0, v.Count())
+
+	_, err = FromArray([]int{1, 2, 3})
+	require.EqualError(err, "vector: expected an array, got []int")
+
+	_, err = FromArray(nil)
+	require.Error(err)
+}
+
+func TestFromSortedMap(t *testing.T) {
+	require := require.New(t)
+
+	v := FromSortedMap(map[int]interface{}{0: "a", 2: "c"})
+	require.True(Equal(New("a", nil, "c"), v))
+
+	v = FromSortedMap(map[int]interface{}{1999: 1, 3: 2})
+	require.Equal(2000, v.Count())
+	require.Equal(2, v.Get(3))
+	require.Equal(1, v.Get(1999))
+	require.Nil(v.Get(0))
+
+	require.Equal(0, FromSortedMap(nil).Count())
+
+	require.Panics(func() {
+		FromSortedMap(map[int]interface{}{-1: "a"})
+	})
+}
+
+func TestIterate(t *testing.T) {
+	require := require.New(t)
+
+	double := func(x interface{}) interface{} {
+		return x.(int) * 2
+	}
+	require.True(Equal(New(1, 2, 4, 8, 16), Iterate(1, 5, double)))
+	require.True(Equal(makeVector(2000), Iterate(0, 2000, func(x interface{}) interface{} {
+		return x.(int) + 1
+	})))
+
+	var calls int
+	v := Iterate("a", 1, func(x interface{}) interface{} {
+		calls++
+		return x
+	})
+	require.True(Equal(New("a"), v))
+	require.Equal(0, calls)
+	require.Equal(0, Iterate(1, 0, double).Count())
+
+	require.Panics(func() { Iterate(1, -1, double) })
+}
+
+func TestUnfold(t *testing.T) {
+	require := require.New(t)
+
+	countdown := Unfold(5, func(state interface{}) (interface{}, interface{}, bool) {
+		n := state.(int)
+		return n, n - 1, n > 0
+	})
+	require.True(Equal(New(5, 4, 3, 2, 1), countdown))
+
+	fib := Unfold([2]int{0, 1}, func(state interface{}) (interface{}, interface{}, bool) {
+		s := state.([2]int)
+		return s[0], [2]int{s[1], s[0] + s[1]}, s[0] < 50
+	})
+	require.True(Equal(New(0, 1, 1, 2, 3, 5, 8, 13, 21, 34), fib))
+
+	empty := Unfold(nil, func(interface{}) (interface{}, interface{}, bool) {
+		return nil, nil, false
+	})
+	require.Equal(0, empty.Count())
+}
+
 func TestAppendAndGet(t *testing.T) {
 	v := New()
 	for i := 0; i < 2000; i++ {
@@ -18,6 +103,77 @@ func TestAppendAndGet(t *testing.T) {
 	}
 }
 
+func TestAppendLevels(t *testing.T) {
+	const n = 1<<15 + 1<<10 + 100
+	v := makeVector(n)
+	for i := 0; i < n; i++ {
+		require.Equal(t, i, v.Get(i))
+	}
+
+	v = New(1, 2, 3).Drop(1).Append(4)
+	require.True(t, Equal(New(2, 3, 4), v))
+}
+
+func TestAppendVector(t *testing.T) {
+	sizes := []int{0, 1, 31, 32, 33, 64, 1024, 1056, 1100, 1 << 15, 1<<15 + 32}
+	for _, n := range sizes {
+		for _, m := range sizes {
+			v := makeVector(n)
+			other := New()
+			for i := 0; i < m; i++ {
+				other = other.Append(n + i)
+			}
+
+			result := v.AppendVector(other)
+			require.Equal(t, n+m, result.Count(), "sizes %d, %d", n, m)
+			for i := 0; i < n+m; i++ {
+				if result.Get(i) != i {
+					require.Equal(t, i, result.Get(i), "sizes %d, %d", n, m)
+				}
+			}
+			require.Equal(t, n+m, result.Append(n+m).Get(n+m))
+		}
+	}
+
+	v := makeVector(64)
+	require.True(t, Equal(
+		New(1, 2, 3, 32, 33),
+		New(0, 1, 2, 3).Drop(1).AppendVector(v.Drop(32).Take(2)),
+	))
+	require.True(t, Equal(
+		makeVector(96).Drop(32),
+		v.Drop(32).AppendVector(makeVector(96).Drop(64)),
+	))
+	require.True(t, Equal(
+		makeVector(96).Drop(1),
+		makeVector(64).Drop(1).AppendVector(makeVector(96).Drop(64)),
+	))
+}
+
+func TestConcatAll(t *testing.T) {
+	require := require.New(t)
+
+	var vs []*Vector
+	for i := 0; i < 100; i++ {
+		vs = append(vs, makeVector(i).Map(func(x interface{}) interface{} {
+			return i
+		}))
+	}
+
+	v := ConcatAll(vs...)
+	require.Equal(99*100/2, v.Count())
+	var idx int
+	for i := 0; i < 100; i++ {
+		for j := 0; j < i; j++ {
+			require.Equal(i, v.Get(idx))
+			idx++
+		}
+	}
+
+	require.True(Equal(New(2, 3, 4, 5), ConcatAll(New(1, 2, 3).Drop(1), nil, New(), New(4, 5))))
+	require.Equal(0, ConcatAll().Count())
+}
+
 func TestGet(t *testing.T) {
 	require := require.New(t)
 
@@ -38,10 +194,165 @@ func TestDrop(t *testing.T) {
 	require.Equal(t, 2, len(New(1, 2, 3, 4).Drop(2).Slice()))
 }
 
+func TestDropRange(t *testing.T) {
+	require := require.New(t)
+
+	v := New("a", "b", "c", "d")
+	require.True(Equal(New("a", "d"), v.DropRange(1, 3)))
+	require.True(Equal(New("a", "b"), v.DropRange(-2, 4)))
+	require.True(Equal(New("c", "d"), v.DropRange(0, 2)))
+	require.True(Equal(New("a"), v.DropRange(1, 4)))
+	require.True(Equal(New("a", "d"), v.DropRange(-3, -1)))
+	require.True(v == v.DropRange(2, 2))
+	require.Equal(0, v.DropRange(0, 4).Count())
+
+	big := makeVector(2000).Drop(10)
+	dropped := big.DropRange(100, 1500)
+	require.Equal(590, dropped.Count())
+	require.Equal(109, dropped.Get(99))
+	require.Equal(1510, dropped.Get(100))
+	require.NoError(dropped.Validate())
+
+	require.Panics(func() { v.DropRange(3, 1) })
+	require.Panics(func() { v.DropRange(0, 5) })
+	require.Panics(func() { v.DropRange(-5, 1) })
+}
+
+func TestKeepRange(t *testing.T) {
+	require := require.New(t)
+
+	v := New("a", "b", "c", "d")
+	require.True(Equal(New("b", "c"), v.KeepRange(1, 3)))
+	require.True(Equal(New("c", "d"), v.KeepRange(-2, 4)))
+	require.Equal(0, v.KeepRange(2, 2).Count())
+
+	big := makeVector(2000)
+	kept := big.KeepRange(1000, 2000)
+	require.Equal(0, kept.start)
+	require.True(Equal(big.Drop(1000), kept))
+	require.False(SharesStructure(big, kept))
+	require.NoError(kept.Validate())
+
+	var elems []interface{}
+	kept.walkNodes(func(n *node) bool {
+		for _, x := range n.values {
+			if _, ok := x.(*node); !ok && x != nil {
+				elems = append(elems, x)
+			}
+		}
+		return true
+	})
+	require.Len(elems, 1000)
+
+	require.Panics(func() { v.KeepRange(3, 1) })
+	require.Panics(func() { v.KeepRange(0, 5) })
+}
+
+func TestIntersperse(t *testing.T) {
+	require := require.New(t)
+
+	require.True(Equal(New(1, 0, 2, 0, 3), New(1, 2, 3).Intersperse(0)))
+	require.True(Equal(New(1), New(1).Intersperse(0)))
+	require.True(Equal(New(), New().Intersperse(0)))
+}
+
+func TestPadLeft(t *testing.T) {
+	require := require.New(t)
+
+	require.True(Equal(New(0, 0, 0, 1, 2), New(1, 2).PadLeft(5, 0)))
+	require.True(Equal(New(1, 2), New(1, 2).PadLeft(2, 0)))
+	require.True(Equal(New(1, 2), New(1, 2).PadLeft(-1, 0)))
+	require.True(Equal(New(0, 3, 4), New(1, 2, 3, 4).Drop(2).PadLeft(3, 0)))
+}
+
+func TestPadRight(t *testing.T) {
+	require := require.New(t)
+
+	require.True(Equal(New(1, 2, 0, 0, 0), New(1, 2).PadRight(5, 0)))
+	require.True(Equal(New(1, 2), New(1, 2).PadRight(1, 0)))
+	require.True(Equal(New(3, 4, 0), New(1, 2, 3, 4).Drop(2).PadRight(3, 0)))
+}
+
+func TestDropEvery(t *testing.T) {
+	require := require.New(t)
+
+	v := New(1, 2, 3, 4, 5)
+	require.True(Equal(New(1, 3, 5), v.DropEvery(2)))
+	require.True(Equal(New(1, 2, 4, 5), v.DropEvery(3)))
+	require.Equal(0, v.DropEvery(1).Count())
+	require.True(Equal(v, v.DropEvery(6)))
+
+	require.Panics(func() { v.DropEvery(0) })
+}
+
+func TestKeepEvery(t *testing.T) {
+	require := require.New(t)
+
+	v := New(1, 2, 3, 4, 5, 6)
+	require.True(Equal(New(2, 4, 6), v.KeepEvery(2)))
+	require.True(Equal(New(3, 6), v.KeepEvery(3)))
+	require.True(Equal(v, v.KeepEvery(1)))
+	require.Equal(0, v.KeepEvery(7).Count())
+
+	require.Panics(func() { v.KeepEvery(0) })
+}
+
 func TestSlice(t *testing.T) {
 	require.Equal(t, []interface{}{1, 2, 3}, New(1, 2, 3).Slice())
 }
 
+func TestTailSlice(t *testing.T) {
+	require := require.New(t)
+
+	v := makeVector(40)
+	tail := v.TailSlice()
+	require.Equal([]interface{}{32, 33, 34, 35, 36, 37, 38, 39}, tail)
+
+	tail[0] = -1
+	require.Equal(32, v.Get(32))
+
+	require.Equal([]interface{}{38, 39}, v.Drop(38).TailSlice())
+	require.Equal(makeVector(32).Slice(), makeVector(32).TailSlice())
+	require.Equal([]interface{}{}, New().TailSlice())
+}
+
+func TestTailLen(t *testing.T) {
+	require := require.New(t)
+
+	v := New()
+	require.Equal(0, v.TailLen())
+	for i := 0; i < 33; i++ {
+		v = v.Append(i)
+		require.True(v.TailLen() <= v.TailCap())
+	}
+	require.Equal(1, v.TailLen())
+	require.Equal(32, v.TailCap())
+	require.Equal(32, makeVector(32).TailLen())
+	require.Equal(2, New(1, 2, 3).Drop(1).TailLen())
+	require.Equal(8, makeVector(40).Drop(30).TailLen())
+	for _, v := range []*Vector{New(1, 2, 3).Drop(1), makeVector(40).Drop(30), makeVector(40).Drop(34)} {
+		require.Len(v.TailSlice(), v.TailLen())
+	}
+
+	var nilVector *Vector
+	require.Equal(0, nilVector.TailLen())
+}
+
+func TestBytes(t *testing.T) {
+	require := require.New(t)
+
+	b, err := New(byte('f'), byte('o'), byte('o')).Bytes()
+	require.NoError(err)
+	require.Equal([]byte("foo"), b)
+
+	b, err = New().Bytes()
+	require.NoError(err)
+	require.Equal([]byte{}, b)
+
+	_, err = New(byte(1), byte(2), 3).Bytes()
+	require.EqualError(err, "vector: element 2 is not a byte: int")
+}
+
 func TestSet(t *testing.T) {
 	require := require.New(t)
 
@@ -59,6 +370,162 @@ func TestSet(t *testing.T) {
 
 	require.Equal(-1, makeVector(10000).Set(0, -1).First())
 }
+
+func TestSetIfChanged(t *testing.T) {
+	require := require.New(t)
+
+	v := makeVector(2000)
+	require.True(v == v.SetIfChanged(1500, 1500))
+	require.True(v == v.SetIfChanged(-1, 1999))
+
+	changed := v.SetIfChanged(1500, -1)
+	require.False(v == changed)
+	require.Equal(-1, changed.Get(1500))
+	require.Equal(1500, v.Get(1500))
+
+	require.Panics(func() {
+		New().SetIfChanged(0, nil)
+	})
+}
+
+func TestOverlay(t *testing.T) {
+	require := require.New(t)
+
+	v := New(1, 2, 3)
+	require.True(Equal(New(9, 9, 3), v.Overlay(New(9, 9))))
+	require.True(Equal(New(9, 8, 7), v.Overlay(New(9, 8, 7, 6))))
+	require.True(v == v.Overlay(New()))
+	require.True(Equal(New(1, 2, 3), v))
+
+	big := makeVector(2000)
+	result := big.Drop(100).Overlay(New(-1, -2))
+	require.Equal(1900, result.Count())
+	require.Equal(-1, result.Get(0))
+	require.Equal(-2, result.Get(1))
+	require.Equal(102, result.Get(2))
+	require.True(SharesStructure(big, result))
+
+	require.Equal(0, New().Overlay(New(1)).Count())
+}
+
+func TestSwapRanges(t *testing.T) {
+	require := require.New(t)
+
+	v := New(0, 1, 2, 3, 4)
+	swapped, err := v.SwapRanges(0, 2, 3, 1)
+	require.NoError(err)
+	require.True(Equal(New(3, 2, 0, 1, 4), swapped))
+
+	swapped, err = v.SwapRanges(3, 1, 0, 2)
+	require.NoError(err)
+	require.True(Equal(New(3, 2, 0, 1, 4), swapped))
+
+	swapped, err = v.SwapRanges(0, 2, 2, 3)
+	require.NoError(err)
+	require.True(Equal(New(2, 3, 4, 0, 1), swapped))
+
+	swapped, err = v.Drop(1).SwapRanges(0, 1, 3, 1)
+	require.NoError(err)
+	require.True(Equal(New(4, 2, 3, 1), swapped))
+
+	_, err = v.SwapRanges(0, 3, 2, 2)
+	require.EqualError(err, "vector: cannot swap overlapping ranges [0, 3) and [2, 4)")
+
+	_, err = v.SwapRanges(0, 1, 4, 2)
+	require.Error(err)
+
+	_, err = v.SwapRanges(-1, 1, 3, 1)
+	require.Error(err)
+}
+
+func TestReplace(t *testing.T) {
+	require := require.New(t)
+
+	v := New(1, 2, 2, 3)
+	require.True(Equal(New(1, 9, 9, 3), v.Replace(2, 9)))
+	require.True(Equal(New(1, 2, 2, 3), v))
+	require.True(v == v.Replace(5, 9))
+
+	big := makeVector(2000).Set(1500, -1)
+	result := big.Replace(-1, 1500)
+	require.True(Equal(makeVector(2000), result))
+	require.True(SharesStructure(big, result))
+	require.NoError(result.Validate())
+
+	dropped := New(2, 1, 2).Drop(1).Replace(2, 9)
+	require.True(Equal(New(1, 9), dropped))
+}
+
+func TestReplaceFirst(t *testing.T) {
+	require := require.New(t)
+
+	v := New(1, 2, 2, 3)
+	require.True(Equal(New(1, 9, 2, 3), v.ReplaceFirst(2, 9)))
+	require.True(v == v.ReplaceFirst(5, 9))
+
+	big := makeVector(2000).Set(100, -1).Set(1900, -1)
+	result := big.ReplaceFirst(-1, 100)
+	require.Equal(100, result.Get(100))
+	require.Equal(-1, result.Get(1900))
+	require.True(SharesStructure(big, result))
+}
+
+func TestReplaceFunc(t *testing.T) {
+	require := require.New(t)
+
+	var visited []interface{}
+	v := New(1, 2, 3, 4, 5).Drop(2).ReplaceFunc(func(x interface{}) bool {
+		visited = append(visited, x)
+		return x.(int)%2 == 1
+	}, 0)
+	require.True(Equal(New(0, 4, 0), v))
+	require.Equal([]interface{}{3, 4, 5}, visited)
+}
+
+func TestMapWhere(t *testing.T) {
+	require := require.New(t)
+
+	isEven := func(x interface{}) bool {
+		return x.(int)%2 == 0
+	}
+	double := func(x interface{}) interface{} {
+		return x.(int) * 2
+	}
+
+	v := New(1, 2, 3, 4)
+	require.True(Equal(New(1, 4, 3, 8), v.MapWhere(isEven, double)))
+	require.True(Equal(New(1, 2, 3, 4), v))
+
+	odd := New(1, 3, 5)
+	require.True(odd == odd.MapWhere(isEven, double))
+
+	big := makeVector(2000)
+	result := big.MapWhere(func(x interface{}) bool {
+		return x.(int) == 1500
+	}, double)
+	require.Equal(3000, result.Get(1500))
+	require.True(SharesStructure(big, result))
+	require.NoError(result.Validate())
+}
+
+func TestCoalesce(t *testing.T) {
+	require := require.New(t)
+
+	v := New(1, nil, 3, nil)
+	require.True(Equal(New(1, 0, 3, 0), v.Coalesce(0)))
+	require.True(Equal(New(1, nil, 3, nil), v))
+
+	full := New(1, 2, 3)
+	require.True(full == full.Coalesce(0))
+
+	sparse := FromSparse(2000, map[int]interface{}{10: "a", 1500: "b"}, nil)
+	filled := sparse.Coalesce("")
+	require.Equal("a", filled.Get(10))
+	require.Equal("b", filled.Get(1500))
+	require.Equal("", filled.Get(1999))
+	require.NoError(filled.Validate())
+}
+
 func TestTail(t *testing.T) {
 	v := New(1, 2, 3)
 	require.True(t, Equal(New(2, 3), v.Tail()))
@@ -97,63 +564,1287 @@ func TestRange(t *testing.T) {
 		return someErr
 	})
 	require.Equal(someErr, err)
-}
 
-func TestEqual(t *testing.T) {
-	require.True(t, Equal(New(1, 2, 3), New(1, 2, 3)))
-	require.False(t, Equal(New(1, 2), New(1, 2, 3)))
-	require.False(t, Equal(New(1, 2, 4), New(1, 2, 3)))
+	result = nil
+	err = makeVector(100).Drop(40).Range(func(elem interface{}) error {
+		result = append(result, elem)
+		return nil
+	})
+	require.NoError(err)
+	require.Equal(makeVector(100).Drop(40).Slice(), result)
 }
 
-func TestVectorString(t *testing.T) {
-	v := New(1, 2, 3, 4, 5)
-	require.Equal(t, v.String(), "[1, 2, 3, 4, 5]")
-}
+func TestRangeTolerant(t *testing.T) {
+	require := require.New(t)
 
-func TestVectorFirst(t *testing.T) {
-	v := New(1, 2, 3, 4, 5)
-	el := v.First()
-	require.Equal(t, el, 1)
-}
+	var visited int
+	notEven := func(x interface{}) error {
+		visited++
+		if x.(int)%2 == 0 {
+			return fmt.Errorf("%d is even", x)
+		}
+		return nil
+	}
 
-func TestVectorLast(t *testing.T) {
-	v := New(1, 2, 3, 4, 5)
-	el := v.Last()
-	require.Equal(t, el, 5)
-}
+	errs, err := makeVector(100).RangeTolerant(3, notEven)
+	require.Equal(ErrTooManyErrors, err)
+	require.Len(errs, 3)
+	require.EqualError(errs[2], "4 is even")
+	require.Equal(5, visited)
 
-func TestMap(t *testing.T) {
-	v := New(1, 2, 3).Map(func(x interface{}) interface{} {
-		return x.(int) * x.(int)
+	visited = 0
+	errs, err = New(1, 2, 3, 4).RangeTolerant(3, notEven)
+	require.NoError(err)
+	require.Len(errs, 2)
+	require.Equal(4, visited)
+
+	visited = 0
+	errs, err = New(2, 1, 3).RangeTolerant(3, func(x interface{}) error {
+		if x == 1 {
+			return ErrStop
+		}
+		return notEven(x)
 	})
+	require.NoError(err)
+	require.Len(errs, 1)
+	require.Equal(1, visited)
 
-	require.True(t, Equal(v, New(1, 4, 9)))
+	errs, err = New().RangeTolerant(1, notEven)
+	require.NoError(err)
+	require.Empty(errs)
+
+	require.Panics(func() {
+		_, _ = New(1).RangeTolerant(0, notEven)
+	})
 }
 
-func TestFilter(t *testing.T) {
-	v := New(1, 2, 3).Filter(func(x interface{}) bool {
-		return x.(int)%2 == 1
+func TestTap(t *testing.T) {
+	require := require.New(t)
+
+	v := makeVector(1000).Drop(3)
+	var visited []interface{}
+	result := v.Tap(func(x interface{}) {
+		visited = append(visited, x)
 	})
 
-	require.True(t, Equal(v, New(1, 3)))
+	require.True(Equal(v, result))
+	require.Equal(v.Slice(), visited)
 }
 
-func BenchmarkAppend(b *testing.B) {
-	v10 := makeVector(10)
-	v100 := makeVector(100)
-	v1000 := makeVector(1000)
+func TestPipe(t *testing.T) {
+	require := require.New(t)
 
-	fn := func(v *Vector) func(*testing.B) {
-		return func(b *testing.B) {
-			for i := 0; i < b.N; i++ {
-				v = v.Append(i)
-			}
+	square := func(v *Vector) *Vector {
+		return v.Map(func(x interface{}) interface{} {
+			return x.(int) * x.(int)
+		})
+	}
+	odd := func(v *Vector) *Vector {
+		return v.Filter(func(x interface{}) bool {
+			return x.(int)%2 == 1
+		})
+	}
+	reverse := func(v *Vector) *Vector {
+		b := newBuilder(v.Count())
+		for i := v.Count() - 1; i >= 0; i-- {
+			b.append(v.Get(i))
 		}
+		return b.vector()
 	}
 
-	b.Run("10", fn(v10))
-	b.Run("100", fn(v100))
-	b.Run("1000", fn(v1000))
+	v := New(1, 2, 3, 4, 5)
+	require.True(Equal(New(25, 9, 1), v.Pipe(square, odd, reverse)))
+	require.True(Equal(New(1, 3, 5), v.Pipe(reverse, odd, reverse)))
+	require.True(v == v.Pipe())
+}
+
+func TestPairwise(t *testing.T) {
+	require := require.New(t)
+
+	var deltas []interface{}
+	err := New(1, 3, 6, 10).Pairwise(func(a, b interface{}) error {
+		deltas = append(deltas, b.(int)-a.(int))
+		return nil
+	})
+	require.NoError(err)
+	require.Equal([]interface{}{2, 3, 4}, deltas)
+
+	var calls int
+	err = New(1).Pairwise(func(a, b interface{}) error {
+		calls++
+		return nil
+	})
+	require.NoError(err)
+	require.Equal(0, calls)
+
+	err = New(1, 2, 3, 4).Pairwise(func(a, b interface{}) error {
+		calls++
+		return ErrStop
+	})
+	require.NoError(err)
+	require.Equal(1, calls)
+
+	someErr := fmt.Errorf("foo")
+	err = New(1, 2).Pairwise(func(a, b interface{}) error {
+		return someErr
+	})
+	require.Equal(someErr, err)
+}
+
+func TestTriples(t *testing.T) {
+	require := require.New(t)
+
+	var triples [][3]interface{}
+	err := New(1, 2, 3, 4, 5).Triples(func(a, b, c interface{}) error {
+		triples = append(triples, [3]interface{}{a, b, c})
+		return nil
+	})
+	require.NoError(err)
+	require.Equal([][3]interface{}{{1, 2, 3}, {2, 3, 4}, {3, 4, 5}}, triples)
+
+	var calls int
+	err = New(1, 2).Triples(func(a, b, c interface{}) error {
+		calls++
+		return nil
+	})
+	require.NoError(err)
+	require.Equal(0, calls)
+
+	err = New(1, 2, 3, 4, 5).Triples(func(a, b, c interface{}) error {
+		calls++
+		return ErrStop
+	})
+	require.NoError(err)
+	require.Equal(1, calls)
+
+	someErr := fmt.Errorf("foo")
+	err = New(1, 2, 3).Triples(func(a, b, c interface{}) error {
+		return someErr
+	})
+	require.Equal(someErr, err)
+}
+
+func TestDeltas(t *testing.T) {
+	sub := func(a, b interface{}) interface{} {
+		return a.(int) - b.(int)
+	}
+
+	require.True(t, Equal(New(2, 3, 4), New(1, 3, 6, 10).Deltas(sub)))
+	require.Equal(t, 0, New(1).Deltas(sub).Count())
+	require.Equal(t, 0, New().Deltas(sub).Count())
+}
+
+func TestIntegrate(t *testing.T) {
+	add := func(a, b interface{}) interface{} {
+		return a.(int) + b.(int)
+	}
+	sub := func(a, b interface{}) interface{} {
+		return a.(int) - b.(int)
+	}
+
+	require.True(t, Equal(New(1, 3, 6, 10), New(2, 3, 4).Integrate(1, add)))
+	require.True(t, Equal(New(5), New().Integrate(5, add)))
+
+	v := makeVector(100).Map(func(x interface{}) interface{} {
+		return x.(int) * x.(int)
+	})
+	require.True(t, Equal(v, v.Deltas(sub).Integrate(v.First(), add)))
+}
+
+func TestCommonPrefix(t *testing.T) {
+	require := require.New(t)
+
+	prefix := CommonPrefix(New(1, 2, 3), New(1, 2, 9), New(1, 2))
+	require.True(Equal(New(1, 2), prefix))
+
+	v := New(1, 2, 3)
+	require.True(v == CommonPrefix(v))
+	require.True(Equal(v, CommonPrefix(v, New(1, 2, 3, 4))))
+	require.Equal(0, CommonPrefix(v, New(2, 3)).Count())
+	require.Equal(0, CommonPrefix(v, New()).Count())
+	require.Equal(0, CommonPrefix().Count())
+
+	big := makeVector(2000)
+	prefix = CommonPrefix(big, big.Set(1500, -1), big.Drop(1).PadLeft(2000, 0))
+	require.True(Equal(makeVector(1500), prefix))
+}
+
+func TestMergeSorted(t *testing.T) {
+	require := require.New(t)
+
+	less := func(a, b interface{}) bool {
+		return a.(int) < b.(int)
+	}
+
+	merged := MergeSorted(New(1, 3, 5), New(2, 4, 6), less)
+	require.True(Equal(New(1, 2, 3, 4, 5, 6), merged))
+
+	merged = MergeSorted(New(1, 2), New(0, 3, 4, 5), less)
+	require.True(Equal(New(0, 1, 2, 3, 4, 5), merged))
+
+	evens, odds := New(), New()
+	for i := 0; i < 2000; i++ {
+		if i%2 == 0 {
+			evens = evens.Append(i)
+		} else {
+			odds = odds.Append(i)
+		}
+	}
+	require.True(Equal(makeVector(2000), MergeSorted(evens, odds, less)))
+	require.True(Equal(makeVector(1000).Drop(10), MergeSorted(makeVector(1000).Drop(10), nil, less)))
+	require.Equal(0, MergeSorted(New(), New(), less).Count())
+
+	// Equal elements are taken from the first vector first.
+	byKey := func(a, b interface{}) bool {
+		return a.([2]int)[0] < b.([2]int)[0]
+	}
+	merged = MergeSorted(New([2]int{1, 1}), New([2]int{1, 2}), byKey)
+	require.True(Equal(New([2]int{1, 1}, [2]int{1, 2}), merged))
+}
+
+func TestEqual(t *testing.T) {
+	require.True(t, Equal(New(1, 2, 3), New(1, 2, 3)))
+	require.False(t, Equal(New(1, 2), New(1, 2, 3)))
+	require.False(t, Equal(New(1, 2, 4), New(1, 2, 3)))
+}
+
+func TestSharesStructure(t *testing.T) {
+	require := require.New(t)
+
+	v := makeVector(100)
+	require.True(SharesStructure(v, v.Set(0, -1)))
+	require.True(SharesStructure(v, v.Append(100)))
+	require.True(SharesStructure(v, v.Drop(10)))
+	require.False(SharesStructure(v, makeVector(100)))
+	require.False(SharesStructure(New(1, 2, 3), New(1, 2, 3)))
+	require.False(SharesStructure(New(), New()))
+
+	v = makeVector(2000)
+	require.True(SharesStructure(v, v.Set(0, -1).Set(1500, -1).Set(1999, -1)))
+}
+
+func TestEqualShared(t *testing.T) {
+	require := require.New(t)
+
+	v := makeVector(5000)
+	require.True(Equal(v, v.Set(2500, -1).Set(2500, 2500)))
+	require.False(Equal(v, v.Set(2500, -1)))
+	require.False(Equal(v, v.Set(4999, -1)))
+	require.True(Equal(v.Drop(40), v.Set(0, -1).Drop(40)))
+	require.False(Equal(v.Drop(40), v.Set(41, -1).Drop(40)))
+	require.True(Equal(v.Drop(10), makeVector(5000).Drop(10)))
+	require.True(Equal(v.Drop(10), New(-1).AppendVector(v).Drop(11)))
+
+	var calls int
+	EqualFunc(v, v.Set(2500, -1), func(a, b interface{}) bool {
+		calls++
+		return a == b
+	})
+	require.True(calls <= 64)
+
+	// Comparing vectors doesn't change them, so they can still be compared
+	// with reflect.DeepEqual as elements of other vectors.
+	a, b := New(1, 2), New(1, 2)
+	require.False(Equal(a, New(1, 3)))
+	require.Equal(b, a)
+	require.Equal(1, New(a, b).Dedupe().Count())
+}
+
+func TestIsEmpty(t *testing.T) {
+	require.True(t, New().IsEmpty())
+	require.True(t, New(1).Tail().IsEmpty())
+	require.False(t, New(1).IsEmpty())
+}
+
+func TestNilVector(t *testing.T) {
+	require := require.New(t)
+
+	var v *Vector
+	require.Nil(v.Get(0))
+	require.Equal(0, v.Count())
+	require.True(v.IsEmpty())
+	require.Equal([]interface{}{}, v.Slice())
+	require.Equal("[]", v.String())
+
+	var calls int
+	require.NoError(v.Range(func(interface{}) error {
+		calls++
+		return nil
+	}))
+	require.Equal(0, calls)
+
+	require.True(Equal(New(1), v.Append(1)))
+}
+
+func TestPartitionPoint(t *testing.T) {
+	require := require.New(t)
+
+	isOne := func(x interface{}) bool {
+		return x == 1
+	}
+	require.Equal(3, New(1, 1, 1, 0, 0).PartitionPoint(isOne))
+	require.Equal(0, New(0, 0).PartitionPoint(isOne))
+	require.Equal(2, New(1, 1).PartitionPoint(isOne))
+	require.Equal(0, New().PartitionPoint(isOne))
+
+	lessThan := func(n int) func(interface{}) bool {
+		return func(x interface{}) bool {
+			return x.(int) < n
+		}
+	}
+	v := makeVector(2000)
+	require.Equal(1234, v.PartitionPoint(lessThan(1234)))
+	require.Equal(1000, v.Drop(234).PartitionPoint(lessThan(1234)))
+}
+
+func TestRangeByValue(t *testing.T) {
+	require := require.New(t)
+
+	cmp := func(a, b interface{}) int {
+		return a.(int) - b.(int)
+	}
+
+	v := makeVector(10)
+	require.True(Equal(New(3, 4, 5, 6, 7), v.RangeByValue(3, 7, cmp)))
+	require.True(Equal(New(8, 9), v.RangeByValue(8, 20, cmp)))
+	require.True(Equal(v, v.RangeByValue(-5, 20, cmp)))
+	require.Equal(0, v.RangeByValue(20, 30, cmp).Count())
+	require.Equal(0, v.RangeByValue(7, 3, cmp).Count())
+	require.True(Equal(New(2, 2, 2), New(1, 2, 2, 2, 3).RangeByValue(2, 2, cmp)))
+}
+
+func TestSplitN(t *testing.T) {
+	require := require.New(t)
+
+	parts := makeVector(10).SplitN(3)
+	require.Len(parts, 3)
+	require.True(Equal(New(0, 1, 2, 3), parts[0]))
+	require.True(Equal(New(4, 5, 6), parts[1]))
+	require.True(Equal(New(7, 8, 9), parts[2]))
+
+	parts = New(1, 2).SplitN(3)
+	require.Len(parts, 3)
+	require.True(Equal(New(1), parts[0]))
+	require.True(Equal(New(2), parts[1]))
+	require.True(Equal(New(), parts[2]))
+
+	v := makeVector(100)
+	parts = v.SplitN(1)
+	require.True(v == parts[0])
+
+	big := makeVector(2000).Drop(10)
+	elems := big.Slice()
+	parts = big.SplitN(3)
+	var lo int
+	for i, size := range []int{664, 663, 663} {
+		require.True(SharesStructure(big, parts[i]), "part %d", i)
+		require.Equal(elems[lo:lo+size], parts[i].Slice())
+		lo += size
+	}
+	require.Equal(1990, lo)
+	require.Equal(-1, parts[0].Append(-1).Get(664))
+	require.Equal(-1, parts[1].Append(-1).Get(663))
+	require.Equal(elems, big.Slice())
+
+	require.Panics(func() { v.SplitN(0) })
+}
+
+func TestReshape(t *testing.T) {
+	require := require.New(t)
+
+	m, err := New(1, 2, 3, 4, 5, 6).Reshape(2)
+	require.NoError(err)
+	require.Equal(3, m.Count())
+	require.True(Equal(New(New(1, 2), New(3, 4), New(5, 6)), m))
+
+	m, err = New().Reshape(3)
+	require.NoError(err)
+	require.Equal(0, m.Count())
+
+	_, err = New(1, 2, 3).Reshape(2)
+	require.EqualError(err, "vector: cannot reshape a vector with 3 elements into rows of 2 elements")
+
+	_, err = New(1, 2, 3).Reshape(0)
+	require.Error(err)
+}
+
+func TestGrid(t *testing.T) {
+	require := require.New(t)
+
+	g, err := New(1, 2, 3, 4, 5, 6).Grid(2, 3)
+	require.NoError(err)
+	require.True(Equal(New(New(1, 2, 3), New(4, 5, 6)), g))
+
+	g, err = New(1, 2, 3, 4, 5, 6, 7).Grid(3, 2)
+	require.NoError(err)
+	require.True(Equal(New(New(1, 2), New(3, 4), New(5, 6)), g))
+
+	_, err = New(1, 2, 3, 4).Grid(2, 3)
+	require.EqualError(err, "vector: cannot make a grid of 2x3 with 4 elements")
+
+	_, err = New(1, 2, 3, 4).Grid(0, 3)
+	require.Error(err)
+}
+
+func TestTranspose(t *testing.T) {
+	require := require.New(t)
+
+	m, err := New(New(1, 2, 3), New(4, 5, 6)).Transpose()
+	require.NoError(err)
+	require.True(Equal(New(New(1, 4), New(2, 5), New(3, 6)), m))
+
+	m, err = New().Transpose()
+	require.NoError(err)
+	require.Equal(0, m.Count())
+
+	_, err = New(New(1, 2), New(3)).Transpose()
+	require.EqualError(err, "vector: row 1 has 1 elements, expected 2")
+
+	_, err = New(New(1, 2), 3).Transpose()
+	require.EqualError(err, "vector: row 1 is not a vector: int")
+}
+
+func TestVectorString(t *testing.T) {
+	v := New(1, 2, 3, 4, 5)
+	require.Equal(t, v.String(), "[1, 2, 3, 4, 5]")
+}
+
+func TestWriteTo(t *testing.T) {
+	require := require.New(t)
+
+	for _, v := range []*Vector{New(), New(1), New(1, "a", nil), makeVector(100)} {
+		var buf bytes.Buffer
+		n, err := v.WriteTo(&buf)
+		require.NoError(err)
+		require.Equal(v.String(), buf.String())
+		require.Equal(int64(buf.Len()), n)
+	}
+
+	w := &limitedWriter{limit: 5}
+	n, err := New(1, 2, 3, 4).WriteTo(w)
+	require.Equal(errShortWrite, err)
+	require.Equal(int64(5), n)
+}
+
+var errShortWrite = errors.New("short write")
+
+type limitedWriter struct {
+	limit int
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	if len(p) > w.limit {
+		n := w.limit
+		w.limit = 0
+		return n, errShortWrite
+	}
+	w.limit -= len(p)
+	return len(p), nil
+}
+
+func TestJoin(t *testing.T) {
+	require := require.New(t)
+
+	v := New(1, 2, 3)
+	require.Equal("1,2,3", v.Join(",", nil))
+	require.Equal("#1; #2; #3", v.Join("; ", func(x interface{}) string {
+		return fmt.Sprintf("#%d", x)
+	}))
+	require.Equal("", New().Join(",", nil))
+}
+
+func TestVectorFirst(t *testing.T) {
+	v := New(1, 2, 3, 4, 5)
+	el := v.First()
+	require.Equal(t, el, 1)
+}
+
+func TestVectorLast(t *testing.T) {
+	v := New(1, 2, 3, 4, 5)
+	el := v.Last()
+	require.Equal(t, el, 5)
+}
+
+func TestMap(t *testing.T) {
+	v := New(1, 2, 3).Map(func(x interface{}) interface{} {
+		return x.(int) * x.(int)
+	})
+
+	require.True(t, Equal(v, New(1, 4, 9)))
+
+	v = New(1, 2, 3).Drop(1).Map(func(x interface{}) interface{} {
+		return x.(int) * x.(int)
+	})
+	require.True(t, Equal(v, New(4, 9)))
+}
+
+func TestFilter(t *testing.T) {
+	v := New(1, 2, 3).Filter(func(x interface{}) bool {
+		return x.(int)%2 == 1
+	})
+
+	require.True(t, Equal(v, New(1, 3)))
+
+	v = New(1, 2, 3).Drop(1).Filter(func(x interface{}) bool {
+		return true
+	})
+	require.True(t, Equal(v, New(2, 3)))
+}
+
+func TestFilterMap(t *testing.T) {
+	v := New(1, 2, 3, 4, 5, 6).FilterMap(func(x interface{}) (interface{}, bool) {
+		n := x.(int)
+		return n * 10, n%2 == 0
+	})
+
+	require.True(t, Equal(New(20, 40, 60), v))
+}
+
+func TestCircularWindow(t *testing.T) {
+	require := require.New(t)
+
+	windows := func(v *Vector, size int) [][]interface{} {
+		var result [][]interface{}
+		err := v.CircularWindow(size, func(window []interface{}) error {
+			result = append(result, append([]interface{}(nil), window...))
+			return nil
+		})
+		require.NoError(err)
+		return result
+	}
+
+	v := New(1, 2, 3)
+	require.Equal([][]interface{}{{1, 2}, {2, 3}, {3, 1}}, windows(v, 2))
+	require.Equal([][]interface{}{{1}, {2}, {3}}, windows(v, 1))
+	require.Equal([][]interface{}{
+		{1, 2, 3, 1},
+		{2, 3, 1, 2},
+		{3, 1, 2, 3},
+	}, windows(v, 4))
+	require.Nil(windows(New(), 2))
+
+	var calls int
+	err := v.CircularWindow(2, func([]interface{}) error {
+		calls++
+		return ErrStop
+	})
+	require.NoError(err)
+	require.Equal(1, calls)
+
+	require.Panics(func() {
+		_ = v.CircularWindow(0, func([]interface{}) error { return nil })
+	})
+}
+
+func TestFlattenDeep(t *testing.T) {
+	require := require.New(t)
+
+	v := New(New(1, New(2, 3)), 4)
+	require.True(Equal(New(1, 2, 3, 4), v.FlattenDeep()))
+	require.True(Equal(New(1, 2), New(New(), 1, New(New(New(2)))).FlattenDeep()))
+	require.True(Equal(New(1, 2, 3), New(1, 2, 3).FlattenDeep()))
+}
+
+func TestFlattenJoin(t *testing.T) {
+	require := require.New(t)
+
+	v, err := New(New(1, 2), New(3)).FlattenJoin(0)
+	require.NoError(err)
+	require.True(Equal(New(1, 2, 0, 3), v))
+
+	v, err = New(New("a"), New(), New(New("b"))).FlattenJoin(",")
+	require.NoError(err)
+	require.True(Equal(New("a", ",", ",", New("b")), v))
+
+	v, err = New(New(1, 2)).FlattenJoin(0)
+	require.NoError(err)
+	require.True(Equal(New(1, 2), v))
+
+	v, err = New().FlattenJoin(0)
+	require.NoError(err)
+	require.Equal(0, v.Count())
+
+	_, err = New(New(1), 2).FlattenJoin(0)
+	require.EqualError(err, "vector: element 1 is not a vector: int")
+}
+
+func TestWithIndex(t *testing.T) {
+	v := New("a", "b", "c", "d").Drop(2).WithIndex()
+	require.True(t, Equal(New(
+		IndexedElem{0, "c"},
+		IndexedElem{1, "d"},
+	), v))
+}
+
+func TestDedupe(t *testing.T) {
+	require.True(t, Equal(New(1, 2, 3, 1), New(1, 1, 2, 2, 2, 3, 1).Dedupe()))
+	require.True(t, Equal(New(nil, 1), New(nil, nil, 1).Dedupe()))
+	require.True(t, Equal(New(), New().Dedupe()))
+}
+
+func TestDedupeFunc(t *testing.T) {
+	sameSign := func(a, b interface{}) bool {
+		return (a.(int) < 0) == (b.(int) < 0)
+	}
+
+	require.True(t, Equal(New(1, -1, 3), New(1, 2, -1, -5, 3).DedupeFunc(sameSign)))
+}
+
+func TestRunLengthEncode(t *testing.T) {
+	require := require.New(t)
+
+	v := New(1, 1, 2, 3, 3, 3, 1)
+	encoded := v.RunLengthEncode()
+	require.True(Equal(New(Run{1, 2}, Run{2, 1}, Run{3, 3}, Run{1, 1}), encoded))
+	require.Equal(0, New().RunLengthEncode().Count())
+
+	decoded, err := encoded.RunLengthDecode()
+	require.NoError(err)
+	require.True(Equal(v, decoded))
+}
+
+func TestRunLengthDecode(t *testing.T) {
+	require := require.New(t)
+
+	v, err := New(Run{"a", 3}, Run{"b", 0}, Run{nil, 1}).RunLengthDecode()
+	require.NoError(err)
+	require.True(Equal(New("a", "a", "a", nil), v))
+
+	_, err = New(Run{"a", 3}, "b").RunLengthDecode()
+	require.EqualError(err, "vector: element 1 is not a run: string")
+}
+
+func TestChunkBy(t *testing.T) {
+	require := require.New(t)
+
+	identity := func(x interface{}) interface{} { return x }
+	chunks := New(1, 1, 2, 2, 1).ChunkBy(identity)
+	require.True(Equal(New(New(1, 1), New(2, 2), New(1)), chunks))
+
+	parity := func(x interface{}) interface{} { return x.(int) % 2 }
+	chunks = New(1, 3, 2, 4, 6, 5).ChunkBy(parity)
+	require.True(Equal(New(New(1, 3), New(2, 4, 6), New(5)), chunks))
+
+	require.Equal(0, New().ChunkBy(identity).Count())
+}
+
+func TestSparse(t *testing.T) {
+	require := require.New(t)
+
+	v := New(0, 1, nil, 0, 2, "", "a", 0)
+	m := v.Sparse()
+	require.Equal(map[int]interface{}{1: 1, 4: 2, 6: "a"}, m)
+
+	require.True(Equal(New(0, 1, 0, 0, 2, 0, "a", 0), FromSparse(v.Count(), m, 0)))
+	require.Equal(0, len(New().Sparse()))
+}
+
+func TestFromSparse(t *testing.T) {
+	require := require.New(t)
+
+	v := FromSparse(40, map[int]interface{}{0: "a", 35: "b"}, nil)
+	require.Equal(40, v.Count())
+	require.Equal("a", v.Get(0))
+	require.Equal("b", v.Get(35))
+	require.Nil(v.Get(34))
+	require.Equal(map[int]interface{}{0: "a", 35: "b"}, v.Sparse())
+
+	require.Equal(0, FromSparse(0, nil, 0).Count())
+
+	require.Panics(func() {
+		FromSparse(3, map[int]interface{}{3: 1}, 0)
+	})
+}
+
+func TestUnzip(t *testing.T) {
+	require := require.New(t)
+
+	v := New(
+		[2]interface{}{1, "a"},
+		[2]interface{}{2, "b"},
+		[2]interface{}{3, "c"},
+	)
+	left, right := v.Unzip(func(x interface{}) (interface{}, interface{}) {
+		pair := x.([2]interface{})
+		return pair[0], pair[1]
+	})
+	require.True(Equal(New(1, 2, 3), left))
+	require.True(Equal(New("a", "b", "c"), right))
+}
+
+func TestIsPalindrome(t *testing.T) {
+	require := require.New(t)
+
+	require.True(New(1, 2, 3, 2, 1).IsPalindrome(nil))
+	require.True(New(1, 2, 2, 1).IsPalindrome(nil))
+	require.True(New(1).IsPalindrome(nil))
+	require.True(New().IsPalindrome(nil))
+	require.False(New(1, 2, 3).IsPalindrome(nil))
+	require.False(New(1, 2, 1, 1).IsPalindrome(nil))
+	require.True(New(0, 1, 2, 1).Drop(1).IsPalindrome(nil))
+
+	sameParity := func(a, b interface{}) bool {
+		return a.(int)%2 == b.(int)%2
+	}
+	require.True(New(1, 2, 3).IsPalindrome(sameParity))
+}
+
+func TestIsMonotonic(t *testing.T) {
+	cmp := func(a, b interface{}) int {
+		return a.(int) - b.(int)
+	}
+
+	testCases := []struct {
+		name       string
+		v          *Vector
+		increasing bool
+		decreasing bool
+	}{
+		{"empty", New(), true, true},
+		{"single", New(1), true, true},
+		{"constant", New(2, 2, 2), true, true},
+		{"increasing", New(1, 2, 3), true, false},
+		{"non-decreasing", New(1, 1, 3), true, false},
+		{"decreasing", New(3, 2, 1), false, true},
+		{"unsorted", New(1, 3, 2), false, false},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			increasing, decreasing := tt.v.IsMonotonic(cmp)
+			require.Equal(t, tt.increasing, increasing)
+			require.Equal(t, tt.decreasing, decreasing)
+		})
+	}
+}
+
+func TestMinMax(t *testing.T) {
+	require := require.New(t)
+
+	less := func(a, b interface{}) bool {
+		return a.(int) < b.(int)
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	for _, n := range []int{1, 2, 3, 10, 101} {
+		v := makeVector(n).Shuffle(rng).Map(func(x interface{}) interface{} {
+			return x.(int) - n/2
+		})
+		min, max, ok := v.MinMax(less)
+		require.True(ok)
+		require.Equal(v.BottomN(1, less).First(), min)
+		require.Equal(v.TopN(1, less).First(), max)
+	}
+
+	_, _, ok := New().MinMax(less)
+	require.False(ok)
+}
+
+func TestArgMax(t *testing.T) {
+	require := require.New(t)
+
+	less := func(a, b interface{}) bool {
+		return a.(int) < b.(int)
+	}
+
+	i, ok := New(3, 1, 4, 1, 5).ArgMax(less)
+	require.True(ok)
+	require.Equal(4, i)
+
+	i, ok = New(5, 1, 5).ArgMax(less)
+	require.True(ok)
+	require.Equal(0, i)
+
+	i, ok = New().ArgMax(less)
+	require.False(ok)
+	require.Equal(-1, i)
+}
+
+func TestArgMin(t *testing.T) {
+	require := require.New(t)
+
+	less := func(a, b interface{}) bool {
+		return a.(int) < b.(int)
+	}
+
+	i, ok := New(3, 1, 4, 1, 5).ArgMin(less)
+	require.True(ok)
+	require.Equal(1, i)
+
+	i, ok = makeVector(2000).Drop(10).ArgMin(less)
+	require.True(ok)
+	require.Equal(0, i)
+
+	_, ok = New().ArgMin(less)
+	require.False(ok)
+}
+
+func TestFirstInvalid(t *testing.T) {
+	require := require.New(t)
+
+	var calls int
+	positive := func(x interface{}) bool {
+		calls++
+		return x.(int) > 0
+	}
+
+	idx, ok := New(1, 2, -3, 4, -5).FirstInvalid(positive)
+	require.True(ok)
+	require.Equal(2, idx)
+	require.Equal(3, calls)
+
+	idx, ok = New(1, 2, 3).FirstInvalid(positive)
+	require.False(ok)
+	require.Equal(-1, idx)
+}
+
+func TestCumulativeMax(t *testing.T) {
+	less := func(a, b interface{}) bool {
+		return a.(int) < b.(int)
+	}
+
+	require.True(t, Equal(New(1, 3, 3, 5, 5), New(1, 3, 2, 5, 4).CumulativeMax(less)))
+	require.True(t, Equal(New(), New().CumulativeMax(less)))
+}
+
+func TestCumulativeMin(t *testing.T) {
+	less := func(a, b interface{}) bool {
+		return a.(int) < b.(int)
+	}
+
+	require.True(t, Equal(New(4, 4, 2, 2, 1), New(4, 5, 2, 3, 1).CumulativeMin(less)))
+	require.True(t, Equal(New(), New().CumulativeMin(less)))
+}
+
+func TestIndicesOf(t *testing.T) {
+	require := require.New(t)
+
+	even := func(x interface{}) bool {
+		return x.(int)%2 == 0
+	}
+
+	indices := makeVector(2000).IndicesOf(even)
+	require.Len(indices, 1000)
+	for i, idx := range indices {
+		require.Equal(i*2, idx)
+	}
+
+	require.Equal([]int{1, 3}, New(1, 2, 3, 4, 5).IndicesOf(even))
+	require.Nil(New(1, 3).IndicesOf(even))
+}
+
+func TestSelect(t *testing.T) {
+	require := require.New(t)
+
+	v := New("a", "b", "c")
+	selected, err := v.Select([]int{2, 0, 2})
+	require.NoError(err)
+	require.True(Equal(New("c", "a", "c"), selected))
+
+	selected, err = v.Select([]int{-1, -3})
+	require.NoError(err)
+	require.True(Equal(New("c", "a"), selected))
+
+	selected, err = makeVector(2000).Drop(1000).Select([]int{0, 999})
+	require.NoError(err)
+	require.True(Equal(New(1000, 1999), selected))
+
+	selected, err = v.Select(nil)
+	require.NoError(err)
+	require.Equal(0, selected.Count())
+
+	_, err = v.Select([]int{0, 3})
+	require.EqualError(err, "vector: index 3 out of bounds of a vector with 3 elements")
+
+	_, err = v.Select([]int{-4})
+	require.Error(err)
+}
+
+func TestScatter(t *testing.T) {
+	require := require.New(t)
+
+	scattered, err := New("a", "b").Scatter([]int{2, 0}, 3)
+	require.NoError(err)
+	require.True(Equal(New("b", nil, "a"), scattered))
+
+	v := New("a", "b", "c")
+	perm := []int{1, 2, 0}
+	scattered, err = v.Scatter(perm, 3)
+	require.NoError(err)
+	selected, err := scattered.Select(perm)
+	require.NoError(err)
+	require.True(Equal(v, selected))
+
+	_, err = New("a", "b").Scatter([]int{1, 1}, 3)
+	require.EqualError(err, "vector: position 1 is repeated")
+
+	_, err = New("a", "b").Scatter([]int{0, 3}, 3)
+	require.EqualError(err, "vector: position 3 out of bounds of a vector with 3 elements")
+
+	_, err = New("a", "b").Scatter([]int{0}, 3)
+	require.Error(err)
+
+	require.Panics(func() {
+		_, _ = New().Scatter(nil, -1)
+	})
+}
+
+func TestCountFuncRange(t *testing.T) {
+	require := require.New(t)
+
+	even := func(x interface{}) bool {
+		return x.(int)%2 == 0
+	}
+
+	v := makeVector(1000)
+	require.Equal(50, v.CountFuncRange(100, 200, even))
+	require.Equal(1, v.CountFuncRange(99, 101, even))
+	require.Equal(0, v.CountFuncRange(10, 10, even))
+	require.Equal(500, v.CountFuncRange(0, 1000, even))
+	require.Equal(5, v.Drop(1).CountFuncRange(0, 10, even))
+
+	require.Panics(func() { v.CountFuncRange(-1, 10, even) })
+	require.Panics(func() { v.CountFuncRange(0, 1001, even) })
+	require.Panics(func() { v.CountFuncRange(10, 5, even) })
+}
+
+func TestHistogram(t *testing.T) {
+	require := require.New(t)
+
+	byTwo := func(x interface{}) int {
+		return x.(int) / 2
+	}
+
+	require.Equal([]int{2, 2, 2, 2, 2}, makeVector(10).Histogram(byTwo, 5))
+	require.Equal([]int{2, 2, 2}, makeVector(10).Histogram(byTwo, 3))
+	require.Equal([]int{0, 1, 0}, New(-2, 3, 100).Histogram(byTwo, 3))
+	require.Equal([]int{}, makeVector(10).Histogram(byTwo, 0))
+
+	require.Panics(func() { New().Histogram(byTwo, -1) })
+}
+
+func TestCDF(t *testing.T) {
+	require := require.New(t)
+
+	byTwo := func(x interface{}) int {
+		return x.(int) / 2
+	}
+
+	cdf := makeVector(10).CDF(byTwo, 5)
+	require.Equal([]float64{0.2, 0.4, 0.6, 0.8, 1}, cdf)
+
+	cdf = New(0, 0, 0, 9, 100).CDF(byTwo, 5)
+	require.Equal(1.0, cdf[len(cdf)-1])
+	for i := 1; i < len(cdf); i++ {
+		require.True(cdf[i-1] <= cdf[i])
+	}
+	require.Equal(0.75, cdf[0])
+
+	require.Equal([]float64{0, 0}, New().CDF(byTwo, 2))
+}
+
+func TestLongestRun(t *testing.T) {
+	isOne := func(x interface{}) bool {
+		return x == 1
+	}
+
+	testCases := []struct {
+		v      *Vector
+		start  int
+		length int
+	}{
+		{New(1, 0, 1, 1, 1, 0, 1), 2, 3},
+		{New(1, 1, 0, 1, 1), 0, 2},
+		{New(0, 1, 1), 1, 2},
+		{New(0, 0), -1, 0},
+		{New(), -1, 0},
+	}
+
+	for _, tt := range testCases {
+		start, length := tt.v.LongestRun(isOne)
+		require.Equal(t, tt.start, start, "vector %s", tt.v)
+		require.Equal(t, tt.length, length, "vector %s", tt.v)
+	}
+}
+
+func TestContainsAll(t *testing.T) {
+	require := require.New(t)
+
+	v := New(1, 2, 3, 4)
+	require.True(v.ContainsAll(New(2, 4), nil))
+	require.True(v.ContainsAll(New(4, 4, 1), nil))
+	require.False(v.ContainsAll(New(2, 9), nil))
+	require.False(v.ContainsAll(New(int64(2)), nil))
+	require.True(v.ContainsAll(New(), nil))
+	require.False(New().ContainsAll(New(1), nil))
+
+	nested := New([]int{1}, nil, New(1, 2))
+	require.True(nested.ContainsAll(New(nil, []int{1}, New(1, 2)), nil))
+	require.False(nested.ContainsAll(New([]int{2}), nil))
+
+	sameParity := func(a, b interface{}) bool {
+		return a.(int)%2 == b.(int)%2
+	}
+	require.True(New(1, 2).ContainsAll(New(3, 5, 8), sameParity))
+	require.False(New(1, 3).ContainsAll(New(3, 8), sameParity))
+}
+
+func TestContainsAny(t *testing.T) {
+	require := require.New(t)
+
+	v := New(1, 2, 3, 4)
+	require.True(v.ContainsAny(New(9, 4), nil))
+	require.False(v.ContainsAny(New(9, 8), nil))
+	require.False(v.ContainsAny(New(), nil))
+	require.True(New("a", []string{"b"}).ContainsAny(New([]string{"b"}), nil))
+
+	sameParity := func(a, b interface{}) bool {
+		return a.(int)%2 == b.(int)%2
+	}
+	require.True(New(1, 3).ContainsAny(New(2, 5), sameParity))
+	require.False(New(1, 3).ContainsAny(New(2, 4), sameParity))
+}
+
+func TestCountSubsequence(t *testing.T) {
+	require := require.New(t)
+
+	require.Equal(2, New(1, 2, 1, 2, 3).CountSubsequence(New(1, 2), nil))
+	require.Equal(1, New(1, 1, 1).CountSubsequence(New(1, 1), nil))
+	require.Equal(0, New(1, 2).CountSubsequence(New(1, 2, 3), nil))
+	require.Equal(0, New(1, 2).CountSubsequence(New(), nil))
+
+	ceq := func(a, b interface{}) bool {
+		return a.(int)%10 == b.(int)%10
+	}
+	require.Equal(2, New(11, 2, 21, 12).CountSubsequence(New(1, 2), ceq))
+}
+
+func TestIndexOfSubsequence(t *testing.T) {
+	require := require.New(t)
+
+	v := New(1, 2, 3, 4)
+	require.Equal(1, v.IndexOfSubsequence(New(2, 3), nil))
+	require.Equal(0, v.IndexOfSubsequence(New(), nil))
+	require.Equal(0, New().IndexOfSubsequence(New(), nil))
+	require.Equal(3, v.IndexOfSubsequence(New(4), nil))
+	require.Equal(-1, v.IndexOfSubsequence(New(3, 2), nil))
+	require.Equal(-1, v.IndexOfSubsequence(New(4, 5), nil))
+	require.Equal(-1, New().IndexOfSubsequence(New(1), nil))
+}
+
+func TestKthSmallest(t *testing.T) {
+	require := require.New(t)
+
+	less := func(a, b interface{}) bool {
+		return a.(int) < b.(int)
+	}
+
+	v := makeVector(101).Shuffle(rand.New(rand.NewSource(1)))
+	median, ok := v.KthSmallest(50, less)
+	require.True(ok)
+	require.Equal(50, median)
+
+	for _, k := range []int{0, 1, 99, 100} {
+		elem, ok := v.KthSmallest(k, less)
+		require.True(ok)
+		require.Equal(k, elem)
+	}
+
+	elem, ok := New(3, 1, 3, 2, 1, 3).KthSmallest(3, less)
+	require.True(ok)
+	require.Equal(3, elem)
+
+	_, ok = v.KthSmallest(101, less)
+	require.False(ok)
+	_, ok = v.KthSmallest(-1, less)
+	require.False(ok)
+	require.Equal(makeVector(101).Shuffle(rand.New(rand.NewSource(1))).Slice(), v.Slice())
+}
+
+func TestCountLeading(t *testing.T) {
+	isZero := func(x interface{}) bool {
+		return x == 0
+	}
+
+	require.Equal(t, 3, New(0, 0, 0, 1).CountLeading(isZero))
+	require.Equal(t, 0, New(1, 0).CountLeading(isZero))
+	require.Equal(t, 2, New(0, 0).CountLeading(isZero))
+	require.Equal(t, 0, New().CountLeading(isZero))
+}
+
+func TestCountTrailing(t *testing.T) {
+	isZero := func(x interface{}) bool {
+		return x == 0
+	}
+
+	require.Equal(t, 0, New(0, 0, 0, 1).CountTrailing(isZero))
+	require.Equal(t, 2, New(1, 0, 0).CountTrailing(isZero))
+	require.Equal(t, 2, New(0, 0).CountTrailing(isZero))
+	require.Equal(t, 0, New().CountTrailing(isZero))
+}
+
+func TestShuffle(t *testing.T) {
+	require := require.New(t)
+
+	v := makeVector(100)
+	a := v.Shuffle(rand.New(rand.NewSource(42)))
+	b := v.Shuffle(rand.New(rand.NewSource(42)))
+	require.True(Equal(a, b))
+	require.False(Equal(a, v))
+
+	elems := a.Slice()
+	sort.Slice(elems, func(i, j int) bool {
+		return elems[i].(int) < elems[j].(int)
+	})
+	require.Equal(v.Slice(), elems)
+
+	require.True(Equal(New(), New().Shuffle(rand.New(rand.NewSource(1)))))
+}
+
+func TestMovingReduce(t *testing.T) {
+	require := require.New(t)
+
+	sum := func(acc, elem interface{}) interface{} {
+		return acc.(int) + elem.(int)
+	}
+
+	v := New(1, 2, 3, 4, 5)
+	require.True(Equal(New(6, 9, 12), v.MovingReduce(3, 0, sum)))
+	require.True(Equal(v, v.MovingReduce(1, 0, sum)))
+	require.True(Equal(New(15), v.MovingReduce(5, 0, sum)))
+	require.Equal(0, v.MovingReduce(6, 0, sum).Count())
+	require.True(Equal(New(9, 12), v.Drop(1).MovingReduce(3, 0, sum)))
+
+	require.Panics(func() {
+		v.MovingReduce(0, 0, sum)
+	})
+}
+
+func TestTopN(t *testing.T) {
+	require := require.New(t)
+
+	less := func(a, b interface{}) bool {
+		return a.(int) < b.(int)
+	}
+
+	v := makeVector(100).Shuffle(rand.New(rand.NewSource(1)))
+	require.True(Equal(New(99, 98, 97), v.TopN(3, less)))
+	require.Equal(0, v.TopN(0, less).Count())
+	require.True(Equal(New(3, 2, 1), New(2, 3, 1).TopN(5, less)))
+	require.True(Equal(New(5, 5), New(5, 1, 5, 2).TopN(2, less)))
+}
+
+func TestBottomN(t *testing.T) {
+	require := require.New(t)
+
+	less := func(a, b interface{}) bool {
+		return a.(int) < b.(int)
+	}
+
+	v := makeVector(100).Shuffle(rand.New(rand.NewSource(1)))
+	require.True(Equal(New(0, 1, 2), v.BottomN(3, less)))
+	require.Equal(0, v.BottomN(-1, less).Count())
+	require.True(Equal(New(1, 2, 3), New(2, 3, 1).BottomN(5, less)))
+}
+
+func BenchmarkEqual(b *testing.B) {
+	v := makeVector(100000)
+
+	b.Run("shared", func(b *testing.B) {
+		other := v.Set(50000, -1).Set(50000, 50000)
+		for i := 0; i < b.N; i++ {
+			_ = Equal(v, other)
+		}
+	})
+
+	b.Run("not shared", func(b *testing.B) {
+		other := makeVector(100000)
+		for i := 0; i < b.N; i++ {
+			_ = Equal(v, other)
+		}
+	})
+
+	// Vectors are built on every iteration, so nothing computed by a previous
+	// comparison can be reused.
+	cases := []struct {
+		name string
+		diff int
+	}{
+		{"fresh, equal", -1},
+		{"fresh, different at 0", 0},
+		{"fresh, different at 50000", 50000},
+	}
+	for _, tt := range cases {
+		b.Run(tt.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				v1, v2 := makeVector(100000), makeVector(100000)
+				if tt.diff >= 0 {
+					v2 = v2.Set(tt.diff, -1)
+				}
+				b.StartTimer()
+				_ = Equal(v1, v2)
+			}
+		})
+	}
+}
+
+func BenchmarkAppend(b *testing.B) {
+	v10 := makeVector(10)
+	v100 := makeVector(100)
+	v1000 := makeVector(1000)
+
+	fn := func(v *Vector) func(*testing.B) {
+		return func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				v = v.Append(i)
+			}
+		}
+	}
+
+	b.Run("10", fn(v10))
+	b.Run("100", fn(v100))
+	b.Run("1000", fn(v1000))
+}
+
+func BenchmarkAppendVector(b *testing.B) {
+	v := makeVector(1024)
+
+	b.Run("aligned", func(b *testing.B) {
+		other := makeVector(1024)
+		for i := 0; i < b.N; i++ {
+			_ = v.AppendVector(other)
+		}
+	})
+
+	b.Run("unaligned", func(b *testing.B) {
+		other := makeVector(1025).Drop(1)
+		for i := 0; i < b.N; i++ {
+			_ = v.AppendVector(other)
+		}
+	})
+}
+
+func BenchmarkConcatAll(b *testing.B) {
+	var vs []*Vector
+	for i := 0; i < 100; i++ {
+		vs = append(vs, makeVector(10))
+	}
+
+	b.Run("ConcatAll", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = ConcatAll(vs...)
+		}
+	})
+
+	b.Run("AppendVector", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			result := New()
+			for _, v := range vs {
+				result = result.AppendVector(v)
+			}
+		}
+	})
 }
 
 func BenchmarkGet(b *testing.B) {